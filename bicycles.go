@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+)
+
+// bicycleRequiredProps содержит свойства, без которых объявление о велосипеде
+// заполняется не полностью. Отсутствующие свойства попадают в отчёт задачи.
+var bicycleRequiredProps = []string{"type", "wheel_diameter", "frame_size", "speeds"}
+
+// bicycleKidsAges содержит значения свойства age, при которых велосипед считается детским.
+var bicycleKidsAges = map[string]bool{
+	"для детей":      true,
+	"для подростков": true,
+}
+
+// defaultBicycleTypes содержит встроенное соответствие значений свойства type
+// из article api значениям тега <VehicleType> Авито.
+// Используется, если справочник BicycleType в хранилище не содержит значения.
+var defaultBicycleTypes = map[string]string{
+	"горный":           "Горные",
+	"фэтбайк":          "Горные",
+	"fatbike":          "Горные",
+	"bmx":              "BMX",
+	"дорожный":         "Дорожные",
+	"городской":        "Дорожные",
+	"шоссейный":        "Дорожные",
+	"гибридный":        "Дорожные",
+	"туристический":    "Дорожные",
+	"складной":         "Дорожные",
+	"электровелосипед": "Дорожные",
+	"детский":          "Детские",
+}
+
+// defaultVehicleType используется, если тип велосипеда не удалось определить.
+const defaultVehicleType = "Дорожные"
+
+// bicycleTags содержит значения тегов Авито для группы товаров bicycles.
+type bicycleTags struct {
+	VehicleType   string
+	WheelDiameter string
+	FrameSize     string
+	Speeds        int
+}
+
+// newBicycleTypeDict создаёт справочник типов велосипедов.
+func newBicycleTypeDict(report *feedReport) *valueDict {
+	return newValueDict("type", defaultBicycleTypes, PricegenStorage.GetAvitoSpec("BicycleType", "type"), report)
+}
+
+// buildBicycleTags формирует значения тегов для группы товаров bicycles.
+// Незаполненные обязательные свойства регистрируются в отчёте задачи.
+func buildBicycleTags(p propReader, bicycleTypes *valueDict) bicycleTags {
+
+	var tags bicycleTags
+
+	for _, prop := range bicycleRequiredProps {
+		if value, _ := p.String(prop); strings.TrimSpace(value) == "" {
+			p.report.addMissing("bicycles", prop, p.key)
+		}
+	}
+
+	age, _ := p.String("age")
+	bicycleType, _ := p.String("type")
+
+	if bicycleKidsAges[strings.ToLower(strings.TrimSpace(age))] {
+		tags.VehicleType = "Детские"
+	} else if vehicleType, ok := bicycleTypes.lookup(bicycleType); ok {
+		tags.VehicleType = vehicleType
+	} else {
+		tags.VehicleType = defaultVehicleType
+	}
+
+	if diameter, ok := p.Raw("wheel_diameter"); ok {
+		if _, ok := inchesTag.value(diameter); ok {
+			tags.WheelDiameter = inchesTag.format(diameter)
+		}
+	}
+
+	if frameSize, ok := p.String("frame_size"); ok {
+		tags.FrameSize = strings.TrimSpace(frameSize)
+	}
+
+	if speeds, ok := p.Int("speeds"); ok && speeds > 0 {
+		tags.Speeds = speeds
+	}
+
+	return tags
+}
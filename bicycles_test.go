@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildBicycleTags(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		props       map[string]any
+		want        bicycleTags
+		wantMissing []string
+	}{
+		{
+			name:  "Kids bicycle",
+			props: map[string]any{"age": "для детей", "type": "горный", "wheel_diameter": "20", "frame_size": "11", "speeds": "6"},
+			want:  bicycleTags{VehicleType: "Детские", WheelDiameter: "20", FrameSize: "11", Speeds: 6},
+		},
+		{
+			name:  "Mountain bicycle with inches",
+			props: map[string]any{"age": "для взрослых", "type": "Горный", "wheel_diameter": "27.5\"", "frame_size": "M", "speeds": 21},
+			want:  bicycleTags{VehicleType: "Горные", WheelDiameter: "27,5", FrameSize: "M", Speeds: 21},
+		},
+		{
+			name:  "Fat bike from storage",
+			props: map[string]any{"type": "фэтбайк", "wheel_diameter": "26 дюймов", "frame_size": "18", "speeds": 7.0},
+			want:  bicycleTags{VehicleType: "Фэтбайки", WheelDiameter: "26", FrameSize: "18", Speeds: 7},
+		},
+		{
+			name:        "Unknown type and missing props",
+			props:       map[string]any{"type": "тандем"},
+			want:        bicycleTags{VehicleType: "Дорожные"},
+			wantMissing: []string{"frame_size", "speeds", "wheel_diameter"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			report := newFeedReport()
+			bicycleTypes := newValueDict("type", defaultBicycleTypes, map[string]string{"Фэтбайк": "Фэтбайки"}, report)
+
+			got := buildBicycleTags(newPropReader(tt.props, "BRAND|NUM", report), bicycleTypes)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("buildBicycleTags() returned unexpected diff (-want +got):\n%s", diff)
+			}
+
+			var gotMissing []string
+			for _, prop := range sortedKeys(report.missing["bicycles"]) {
+				gotMissing = append(gotMissing, prop)
+			}
+			if !cmp.Equal(gotMissing, tt.wantMissing) {
+				t.Errorf("missing props = %v, want %v", gotMissing, tt.wantMissing)
+			}
+		})
+	}
+}
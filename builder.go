@@ -0,0 +1,56 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
+)
+
+// offerBuilder содержит параметры и справочники, общие для формирования
+// всех предложений одной задачи.
+type offerBuilder struct {
+	avito          *avitoParams
+	params         Params
+	goodsGroupPack map[string]map[string]map[string]string
+
+	regexpsIncludedDescription []*regexp.Regexp
+	regexpsExcludedDescription []*regexp.Regexp
+	regexpTiresModel           *regexp.Regexp
+
+	properties                    map[string]Properties
+	avitoModels                   AvitoModelsStruct
+	avitoCategoriesTags           map[string]AvitoCategoriesTagsStruct
+	avitoDescrCategoriesTags      map[string]AvitoCategoriesTagsStruct
+	avitoTruckDescrCategoriesTags map[string]AvitoCategoriesTagsStruct
+	avitoBrandCategoriesTags      map[string]AvitoCategoriesTagsStruct
+	avitoOemSpec                  map[string]string
+	avitoAtfSpec                  map[string]string
+
+	mountingTypes    *valueDict
+	bicycleTypes     *valueDict
+	translator       *propTranslator
+	descriptionProps *descriptionProps
+
+	description         *descriptionTemplate
+	defaultDescription  *descriptionTemplate
+	titles              *titleTemplates
+	titleShortener      *titleShortener
+	imageChecker        *imageChecker
+	imagePolicy         imagePolicy
+	imageProvider       ImageURLProvider
+	placeholderProvider ImageURLProvider
+	placeholders        placeholderImages
+	imageVersions       imageVersions
+	donorLabels         donorLabels
+	offerIDTemplate     *offerIDTemplate
+	offerIDStore        *offerids.Store
+	dateEnd             *dateSchedule
+	dateBegin           *dateSchedule
+	location            *time.Location
+	now                 func() time.Time // nil - time.Now
+	promotion           *promotionRules
+
+	report *feedReport
+	pfx    string
+}
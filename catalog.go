@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
 	"reflect"
 	"regexp"
@@ -17,6 +18,7 @@ import (
 	"golang.org/x/text/language"
 
 	"gitlab.nodasoft.com/lib/gotypes"
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
 )
 
 type avitoParams struct {
@@ -61,6 +63,61 @@ type avitoParams struct {
 	UpdatePhoto         bool `json:"updatePhoto"`
 	UpdatePhotoCount    int  `json:"updatePhotoCount"`
 	FilterOffersPicture int  `json:"filterOffersPicture"` // 0 - не использовать, 1 - Оставлять товары с изображениями, 2 - Оставлять товары без изображений
+	PositionErrorsLimit *int `json:"positionErrorsLimit"` // не задано - defaultPositionErrorsLimit, 0 - ошибка на первой позиции, <0 - без ограничения
+	// TranslatedProps содержит переводы значений свойств клиента.
+	// Ключ - значение свойства или "имя свойства|значение" (см. propTranslator).
+	TranslatedProps map[string]string `json:"translatedProps"`
+	// DescriptionProps содержит дополнительные свойства клиента, выводимые в описании, по группам товаров.
+	DescriptionProps map[string][]string `json:"descriptionProps"`
+	// DescriptionTemplate содержит шаблон описания клиента (text/template, см. descriptionData).
+	// Если не задан, используется встроенный шаблон.
+	DescriptionTemplate string `json:"descriptionTemplate"`
+	// TitleTemplates содержит шаблоны названий клиента по категориям и группам товаров (см. titleTemplate).
+	TitleTemplates map[string]string `json:"titleTemplates"`
+	// VerifyImages включает проверку доступности изображений HEAD-запросами (см. imageChecker).
+	VerifyImages     bool `json:"verifyImages"`
+	VerifyImagesRate int  `json:"verifyImagesRate"` // запросов в секунду, 0 - defaultImageCheckRate
+	VerifyImagesTTL  int  `json:"verifyImagesTTL"`  // часов, 0 - defaultImageCheckTTL
+	// ImagePolicy содержит настройки выбора изображений (см. imagePolicy).
+	ImagePolicy *imagePolicyParams `json:"imagePolicy"`
+	// ImageProvider содержит имя поставщика ссылок на изображения (см. ImageURLProvider),
+	// пустое значение - автоматический выбор по AlternativeImageProxy.
+	ImageProvider   string `json:"imageProvider"`
+	ImageURLPattern string `json:"imageURLPattern"` // шаблон ссылки для поставщика pattern
+	// Placeholders содержит заглушки клиента для предложений без изображений при AlwaysGenerateImage.
+	Placeholders *placeholderParams `json:"placeholders"`
+	// ImageSignSecret включает подпись ссылок альтернативного прокси (см. пакет imagesign).
+	// ImageSignKeyID обязателен и уникален для клиента: по нему прокси выбирает секрет.
+	// Ссылки меняются раз в период ImageSignPeriod или при смене ключа: для отзыва ссылок
+	// задаётся новый секрет и новая версия.
+	ImageSignSecret     string `json:"imageSignSecret"`
+	ImageSignKeyID      string `json:"imageSignKeyID"`
+	ImageSignKeyVersion int    `json:"imageSignKeyVersion"` // 0 - defaultImageSignKeyVersion
+	ImageSignPeriod     int    `json:"imageSignPeriod"`     // дней, 0 - defaultImageSignPeriod
+	// ImageNameVersion содержит версию кодирования имён изображений (см. пакет imagename), 0 - imagename.DefaultVersion.
+	ImageNameVersion int    `json:"imageNameVersion"`
+	ImageNameSeed    string `json:"imageNameSeed"` // ключ клиента для версий кодирования начиная со 2
+	// ImageRefresh содержит источник версий изображений (см. imageVersions), пустое значение - без версий.
+	ImageRefresh  string `json:"imageRefresh"`
+	ImageManifest string `json:"imageManifest"` // путь к манифесту для ImageRefresh "manifest"
+	// OfferIDStore содержит путь к файлу идентификаторов выгруженных предложений (см. пакет offerids).
+	// Если задан, идентификаторы позиций не меняются при смене AvitoOfferID, OfferIDTemplate и RouteID.
+	// Выгрузка и загрузка хранилища - команда cmd/offerids.
+	OfferIDStore string `json:"offerIDStore"`
+	// OfferIDTemplate содержит шаблон идентификатора предложения (см. offerIDTemplate),
+	// пустое значение - идентификатор по режиму AvitoOfferID.
+	OfferIDTemplate   string `json:"offerIDTemplate"`
+	OfferIDCollisions string `json:"offerIDCollisions"` // правило совпадения идентификаторов, см. offerIDKeepFirst
+	// DateEndRule содержит расписание тега <DateEnd> (см. dateSchedule), имеет приоритет над DateEnd.
+	DateEndRule string `json:"dateEndRule"`
+	// DateBegin содержит расписание тега <DateBegin> для отложенной публикации объявлений.
+	DateBegin string `json:"dateBegin"`
+	// Timezone содержит часовой пояс клиента для расчёта дат (IANA), пустое значение - часовой пояс сервера.
+	Timezone string `json:"timezone"`
+	// Promotion содержит правила платного продвижения объявлений (см. promotionRules).
+	// Если не заданы, все объявления получают статус AdStatus. При MaxPromoted
+	// до конца задачи в памяти удерживается не более MaxPromoted продвигаемых объявлений.
+	Promotion *promotionParams `json:"promotion"`
 }
 
 // AvitoModelsStruct описывает структуру Авито моделей шин.
@@ -126,682 +183,691 @@ func (s *offersTaskStock) processXMLStock(inputChan <-chan []position, avito *av
 	goodsGroupPack map[string]map[string]map[string]string, regexpsIncludedDesc, regexpsExcludedDesc []*regexp.Regexp) {
 
 	defer close(s.output)
+	defer drainPositionsOnError(inputChan, &s.err)
+	defer recoverTaskPanic(&s.err)
 
-	var pns map[string]string
 	offers := make(map[string]xmlOfferStock)
-	pfx := "0005"
 	properties, err := PricegenStorage.ParseProperties(avito.PropertiesURL)
 	if err != nil {
 		s.err = err
 		return
 	}
-	for positions := range inputChan {
-		for _, pos := range positions {
-			var offerImages []xmlImage
-			pns = goodsGroupPack[pos.GoodsGroupCode]["pns"]
-			key := strings.ToUpper(pos.Brand + "|" + pos.Number)
-			props := copyMap(properties[key])
 
-			offerID := buildOfferID(pos, avito.AvitoOfferID)
+	report := newFeedReport()
+	defer report.log("Задача обновления остатков")
+
+	b := &offerBuilder{
+		avito:                      avito,
+		params:                     params,
+		goodsGroupPack:             goodsGroupPack,
+		regexpsIncludedDescription: regexpsIncludedDesc,
+		regexpsExcludedDescription: regexpsExcludedDesc,
+		properties:                 properties,
+		mountingTypes:              newMountingTypeDict(report),
+		translator:                 newPropTranslator(goodsGroupPack, avito.TranslatedProps),
+		descriptionProps:           newDescriptionProps(avito.DescriptionProps),
+		imagePolicy:                newImagePolicy(avito),
+		placeholders:               newPlaceholderImages(avito.Placeholders),
+		donorLabels:                newDonorLabels(params.Localization),
+		report:                     report,
+		pfx:                        "0005",
+	}
+	b.description, b.defaultDescription = loadDescriptionTemplates(avito.DescriptionTemplate, params.PriorityDescriptionSource)
+	b.imageProvider, b.placeholderProvider, err = newImageURLProviders(avito, b.pfx)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if b.imageVersions, err = newImageVersions(avito); err != nil {
+		s.err = err
+		return
+	}
+	if b.offerIDTemplate, err = newOfferIDTemplate(avito.OfferIDTemplate); err != nil {
+		s.err = err
+		return
+	}
+	if b.offerIDStore, err = offerids.Open(avito.OfferIDStore); err != nil {
+		s.err = err
+		return
+	}
+	defer b.offerIDStore.Close()
+	collisions, err := newOfferIDCollisions(avito.OfferIDCollisions, b.offerIDStore, report)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if avito.VerifyImages {
+		b.imageChecker = newImageChecker(getImageCheckCache(), avito.VerifyImagesRate, time.Duration(avito.VerifyImagesTTL)*time.Hour)
+		defer b.imageChecker.close()
+	}
 
-			offer := xmlOfferStock{
-				ID:    offerID,
-				Stock: pos.Availability,
-			}
+	errorsLimit := avito.positionErrorsLimit()
 
-			var xmlParams []xmlParam
-			if pos.GoodsGroupCode == "" || pos.GoodsGroupCode == "others" {
-				if props["goods_group"] != nil {
-					xmlParams = getXMLParams(props, pns, params.PriorityDescriptionSource)
-				}
-			} else {
-				xmlParams = getXMLParams(props, pns, params.PriorityDescriptionSource)
-			}
+	for positions := range inputChan {
+		for _, pos := range positions {
 
-			if pos.GoodsGroupCode == "wipers" || props["goods_group"] == "wipers" {
-				xmlParams = append(xmlParams, addWipersParams(pos.Brand)...)
+			offer, ok := guardPosition(pos, report, b.buildOfferStock)
+			if report.positionErrorsExceeded(errorsLimit) {
+				s.err = errors.Wrapf(errPositionErrorsLimit, "обновление остатков, ошибок: %d", report.positionErrorsCount())
+				return
 			}
 
-			productDetail := buildProductDetail(xmlParams)
-
-			desc := buildOfferDescription(pos, productDetail, params.PriorityDescriptionSource, params.Localization, avito.RemoveStatementTypeFromDescr)
-			if excludeOfferWithDescriptions(params.IncludedDescriptions, params.ExcludedDescriptions, desc, regexpsIncludedDesc, regexpsExcludedDesc) {
-				continue
+			if ok {
+				if offer.ID, ok = collisions.add(offer.ID, newOfferCandidate(pos)); ok {
+					offers[offer.ID] = offer
+				}
 			}
+		}
+		if collisions.buffered() {
+			continue
+		}
+		var content []xmlOfferStock
+		for _, v := range offers {
+			content = append(content, v)
+		}
+		offers = make(map[string]xmlOfferStock)
+		s.count += len(content)
+		s.output <- content
+	}
 
-			if imgProp, ok := props["images"]; ok {
-				if imgs, ok := imgProp.([]any); ok && len(imgs) != 0 {
-					for _, img := range imgs {
-
-						imgURL := buildImgURLOffer(img.(string), pfx, avito.AlternativeImageProxy, pos.Brand, pos.Number,
-							avito.DisableAlternativeImage, avito.UpdatePhoto, avito.UpdatePhotoCount)
-						imgURL = addImageIncParam(imgURL, params.ImageInc)
-						offerImages = append(offerImages, xmlImage{URL: imgURL})
-
-						if avito.AlternativeImageProxy != "" {
-							break
-						}
+	if collisions.buffered() && len(offers) != 0 {
+		var content []xmlOfferStock
+		for _, v := range offers {
+			content = append(content, v)
+		}
+		s.count += len(content)
+		s.output <- content
+	}
+}
 
-						if len(offerImages) == 10 {
-							break
-						}
-					}
-				}
-			}
+// buildOfferStock формирует предложение для фида остатков по позиции прайса.
+// Возвращает false, если позиция не должна попасть в фид.
+func (b *offerBuilder) buildOfferStock(pos position) (xmlOfferStock, bool) {
 
-			if len(offerImages) == 0 && avito.AlternativeImageProxy != "" && avito.AlwaysGenerateImage {
-				emptyImg := "05c40c050e1eeef58efb8bcf8e6ce2510b.png"
-				if avito.AlternativeImageRequestMethod == "URL-JPG" {
-					emptyImg = "1149f97a082eb731bab1e4d0bb281be3e8.jpg"
-				}
+	pns := b.goodsGroupPack[pos.GoodsGroupCode]["pns"]
+	key := strings.ToUpper(pos.Brand + "|" + pos.Number)
+	props := copyMap(b.properties[key])
+	p := newPropReader(props, key, b.report)
 
-				imgURL := strings.TrimSuffix(avito.AlternativeImageProxy, "/") +
-					"/images/" + strings.ToLower(replaceModAutorus(pos.Brand, false)) + "/" + strings.ToLower(replaceModAutorus(pos.Number, true)) +
-					"/full/" + emptyImg
-				imgURL = addImageIncParam(imgURL, params.ImageInc)
+	group := pos.GoodsGroupCode
+	if group == "" {
+		group, _ = p.String("goods_group")
+	}
+	b.translator.translate(props, group)
 
-				offerImages = append(offerImages, xmlImage{imgURL})
-			}
+	offerID := b.offerID(pos)
 
-			// Блок для работы с бу товарами
-			if pos.Condition != 0 {
-				offerImages = make([]xmlImage, 0)
-				for _, img := range pos.UsedImages {
+	offer := xmlOfferStock{
+		ID:    offerID,
+		Stock: pos.Availability,
+	}
 
-					imgURL := buildImgURLOffer(img, pfx, avito.AlternativeImageProxy, pos.Brand, pos.Number, avito.DisableAlternativeImage, avito.UpdatePhoto, avito.UpdatePhotoCount)
-					imgURL = addImageIncParam(imgURL, params.ImageInc)
-					offerImages = append(offerImages, xmlImage{URL: imgURL})
+	xmlParams := b.buildXMLParams(pos, props, pns)
 
-					if avito.AlternativeImageProxy != "" {
-						break
-					}
+	desc := b.buildDescription(pos, props, key, xmlParams, "")
+	if excludeOfferWithDescriptions(b.params.IncludedDescriptions, b.params.ExcludedDescriptions, desc, b.regexpsIncludedDescription, b.regexpsExcludedDescription) {
+		return offer, false
+	}
 
-					if len(offerImages) == 10 {
-						break
-					}
-				}
-			}
-			if len(offerImages) == 0 && avito.ExcludeOffersWithoutPicture {
-				continue
-			}
-			offers[offer.ID] = offer
-		}
-		var content []xmlOfferStock
-		for _, v := range offers {
-			content = append(content, v)
-		}
-		offers = make(map[string]xmlOfferStock)
-		s.count += len(offers)
-		s.output <- content
+	offerImages := b.buildImages(pos, p, key)
+	if len(offerImages) == 0 && b.avito.ExcludeOffersWithoutPicture {
+		return offer, false
 	}
+	return offer, true
 }
 
 func (s *offersTask) processXML(posChan <-chan []position, avito *avitoParams, params Params,
 	goodsGroupPack map[string]map[string]map[string]string, regexpsIncludedDescription, regexpsExcludedDescription []*regexp.Regexp) {
 
 	defer close(s.output)
-
-	var (
-		pns           map[string]string
-		propTranslate map[string]string
-		avitoModels   AvitoModelsStruct
-	)
+	defer drainPositionsOnError(posChan, &s.err)
+	defer recoverTaskPanic(&s.err)
 
 	if avito == nil {
 		s.err = errNoParams
 		return
 	}
 
+	if len([]rune(avito.Address)) > 256 {
+		s.err = fmt.Errorf("address more than 256 characters")
+		return
+	}
+
+	if len([]rune(avito.ManagerName)) > 40 {
+		s.err = fmt.Errorf("manager Name more than 40 characters")
+		return
+	}
+
 	regexpTiresModel, err := regexp.Compile("[^a-zA-Zа-яА-ЯёЁ0-9]")
 	if err != nil {
 		log.Errorf("Ошибка компиляции регулярного выражения для моделей шин")
 	}
 
-	offers := make(map[string]xmlOffer)
+	offers := make(map[string]pricedOffer)
 
 	properties, err := PricegenStorage.ParseProperties(avito.PropertiesURL)
 	if err != nil {
 		s.err = err
 		return
 	}
-	avitoCategoriesTags := PricegenStorage.GetAvitoCategoriesTags()
-	avitoDescrCategoriesTags := PricegenStorage.GetAvitoDescrCategoriesTags()
-	avitoTruckDescrCategoriesTags := PricegenStorage.GetAvitoTruckDescrCategoriesTags()
-	avitoBrandCategoriesTags := PricegenStorage.GetAvitoBrandCategoriesTags()
-	avitoOemSpec := PricegenStorage.GetAvitoSpec("OemSpec", "oem_spec")
-	avitoAtfSpec := PricegenStorage.GetAvitoSpec("ATF", "atf_spec")
 
-	pfx := "0005"
+	report := newFeedReport()
+	defer report.log(fmt.Sprintf("Задача %d", s.id))
+
+	b := &offerBuilder{
+		avito:                         avito,
+		params:                        params,
+		goodsGroupPack:                goodsGroupPack,
+		regexpsIncludedDescription:    regexpsIncludedDescription,
+		regexpsExcludedDescription:    regexpsExcludedDescription,
+		regexpTiresModel:              regexpTiresModel,
+		properties:                    properties,
+		avitoCategoriesTags:           PricegenStorage.GetAvitoCategoriesTags(),
+		avitoDescrCategoriesTags:      PricegenStorage.GetAvitoDescrCategoriesTags(),
+		avitoTruckDescrCategoriesTags: PricegenStorage.GetAvitoTruckDescrCategoriesTags(),
+		avitoBrandCategoriesTags:      PricegenStorage.GetAvitoBrandCategoriesTags(),
+		avitoOemSpec:                  PricegenStorage.GetAvitoSpec("OemSpec", "oem_spec"),
+		avitoAtfSpec:                  PricegenStorage.GetAvitoSpec("ATF", "atf_spec"),
+		mountingTypes:                 newMountingTypeDict(report),
+		bicycleTypes:                  newBicycleTypeDict(report),
+		translator:                    newPropTranslator(goodsGroupPack, avito.TranslatedProps),
+		descriptionProps:              newDescriptionProps(avito.DescriptionProps),
+		titles:                        newTitleTemplates(avito.TitleTemplates),
+		titleShortener:                newTitleShortener(),
+		imagePolicy:                   newImagePolicy(avito),
+		placeholders:                  newPlaceholderImages(avito.Placeholders),
+		donorLabels:                   newDonorLabels(params.Localization),
+		report:                        report,
+		pfx:                           "0005",
+	}
+	b.description, b.defaultDescription = loadDescriptionTemplates(avito.DescriptionTemplate, params.PriorityDescriptionSource)
+	b.imageProvider, b.placeholderProvider, err = newImageURLProviders(avito, b.pfx)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if b.imageVersions, err = newImageVersions(avito); err != nil {
+		s.err = err
+		return
+	}
+	if b.offerIDTemplate, err = newOfferIDTemplate(avito.OfferIDTemplate); err != nil {
+		s.err = err
+		return
+	}
+	if b.offerIDStore, err = offerids.Open(avito.OfferIDStore); err != nil {
+		s.err = err
+		return
+	}
+	defer b.offerIDStore.Close()
+	collisions, err := newOfferIDCollisions(avito.OfferIDCollisions, b.offerIDStore, report)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if b.dateEnd, b.dateBegin, b.location, err = newDateSchedules(avito); err != nil {
+		s.err = err
+		return
+	}
+	if b.promotion, err = newPromotionRules(avito.Promotion); err != nil {
+		s.err = err
+		return
+	}
+	promotions := newPromotionSummary(buildAdStatus(avito.AdStatus), b.promotion.maxPromoted())
+	defer promotions.log(fmt.Sprintf("Задача %d", s.id))
+	if avito.VerifyImages {
+		b.imageChecker = newImageChecker(getImageCheckCache(), avito.VerifyImagesRate, time.Duration(avito.VerifyImagesTTL)*time.Hour)
+		defer b.imageChecker.close()
+	}
+
+	errorsLimit := avito.positionErrorsLimit()
+	// Предложения, которые может заменить позиция следующего пакета, выгружаются после всех пакетов.
+	buffered := collisions.buffered()
 
 	for positions := range posChan {
 		for _, pos := range positions {
 
-			propTranslate = goodsGroupPack[pos.GoodsGroupCode]["translatedprops"]
-			offerID := buildOfferID(pos, avito.AvitoOfferID)
-
-			key := strings.ToUpper(pos.Brand + "|" + pos.Number)
-			props := copyMap(properties[key])
-			if pos.GoodsGroupCode == "" {
-				if _, ok := props["goods_group"]; ok {
-					pos.GoodsGroupCode = props["goods_group"].(string)
-				}
-
+			offer, ok := guardPosition(pos, report, b.buildOffer)
+			if report.positionErrorsExceeded(errorsLimit) {
+				s.err = errors.Wrapf(errPositionErrorsLimit, "задача %d, ошибок: %d", s.id, report.positionErrorsCount())
+				return
 			}
 
-			pns = goodsGroupPack[pos.GoodsGroupCode]["pns"]
-			for name, val := range props {
-				switch props[name].(type) {
-				case string:
-					if _, ok := propTranslate[props[name].(string)]; ok && propTranslate[props[name].(string)] != "" {
-						props[name] = propTranslate[props[name].(string)]
-					} else {
-						props[name] = val
-					}
-				case []any:
-					var translArr []any
-					for _, va := range props[name].([]any) {
-						if ss, ok := propTranslate[va.(string)]; ok {
-							if ss != "" {
-								translArr = append(translArr, ss)
-							} else {
-								translArr = append(translArr, va)
-							}
-						} else {
-							translArr = append(translArr, va)
-						}
-
-					}
-					props[name] = translArr
-				default:
-					props[name] = val
+			if ok {
+				if offer.ID, ok = collisions.add(offer.ID, newOfferCandidate(pos)); ok {
+					offers[offer.ID] = pricedOffer{offer: offer, price: pos.PriceSale}
 				}
 			}
+		}
 
-			var offer xmlOffer
-			price := int(math.Ceil(pos.PriceSale))
-
-			if pos.isTires() {
-				if len(avitoModels.Response) == 0 {
-					avitoModels = PricegenStorage.GetAvitoModels()
-				}
+		if buffered {
+			continue
+		}
 
-				if pos.Condition != 0 {
-					curTime := currentTime{time.Now()}
-					offer.TireYear = curTime.getTireYear(avito.TireYear)
-				}
+		var priced []pricedOffer
+		for _, v := range offers {
+			priced = append(priced, v)
+		}
 
-				offer.Model = buildOfferModel(getString(props["catalog_model"]), avitoModels, regexpTiresModel)
-			}
+		offers = make(map[string]pricedOffer)
+		content := promotions.admit(priced)
+		s.count += len(content)
+		promotions.count(content)
+		s.output <- content
+	}
 
-			offer.ID = offerID
-			if len([]rune(avito.Address)) > 256 {
-				s.err = fmt.Errorf("address more than 256 characters")
-				return
-			}
-			offer.Address = avito.Address
+	var priced []pricedOffer
+	for _, v := range offers {
+		priced = append(priced, v)
+	}
+	content := append(promotions.admit(priced), promotions.flush()...)
 
-			if len(avito.AdditionalAddresses) != 0 {
-				avito.AdditionalAddresses = deleteDuplicateAddrs(avito.AdditionalAddresses)
-				offer.Addresses = getAddresses(avito.AdditionalAddresses)
-			}
+	if len(content) != 0 {
+		s.count += len(content)
+		promotions.count(content)
+		s.output <- content
+	}
 
-			if len(avito.DisplayAreas) > 0 {
-				displayAreas := make([]string, 0)
-				displayAreas = append(displayAreas, avito.DisplayAreas...)
-				offer.DisplayAreas = &displayAreas
-			}
+	log.Printf("Задача %d: определены офферы для %d позиций", s.id, s.count)
+}
 
-			offer.ContactPhone = avito.ContactPhone
+// buildOffer формирует предложение по позиции прайса.
+// Возвращает false, если позиция не должна попасть в фид.
+func (b *offerBuilder) buildOffer(pos position) (xmlOffer, bool) {
 
-			if len([]rune(avito.ManagerName)) > 40 {
-				s.err = fmt.Errorf("manager Name more than 40 characters")
-				return
-			}
-			offer.ManagerName = avito.ManagerName
+	offerID := b.offerID(pos)
 
-			if pos.isDisks() {
-				offer.RimBrand = pos.Brand
-			} else {
-				offer.Brand = pos.Brand
-			}
+	key := strings.ToUpper(pos.Brand + "|" + pos.Number)
+	props := copyMap(b.properties[key])
+	p := newPropReader(props, key, b.report)
+	if pos.GoodsGroupCode == "" {
+		if goodsGroup, ok := p.String("goods_group"); ok {
+			pos.GoodsGroupCode = goodsGroup
+		}
+	}
 
-			offer.DateEnd = buildDateEnd(time.Now(), avito.DateEnd)
-			offer.ListingFee = buildListingFee(avito.ListingFee)
-			offer.AdStatus = buildAdStatus(avito.AdStatus)
-			offer.ContactMethod = buildContactMethod(avito.ContactMethod)
-			offer.AdType = buildAdType(avito.AdType)
-			offer.Condition = buildCondition(avito.Condition)
+	pns := b.goodsGroupPack[pos.GoodsGroupCode]["pns"]
+	b.translator.translate(props, pos.GoodsGroupCode)
 
-			if imgProp, ok := props["images"]; ok {
-				if imgs, ok := imgProp.([]any); ok && len(imgs) != 0 {
-					for _, img := range imgs {
+	var offer xmlOffer
+	price := int(math.Ceil(pos.PriceSale))
 
-						imgURL := buildImgURLOffer(img.(string), pfx, avito.AlternativeImageProxy, pos.Brand, pos.Number, avito.DisableAlternativeImage, avito.UpdatePhoto, avito.UpdatePhotoCount)
-						imgURL = addImageIncParam(imgURL, params.ImageInc)
-						offer.Images = append(offer.Images, xmlImage{URL: imgURL})
+	if pos.isTires() {
+		if len(b.avitoModels.Response) == 0 {
+			b.avitoModels = PricegenStorage.GetAvitoModels()
+		}
 
-						if avito.AlternativeImageProxy != "" {
-							break
-						}
+		if pos.Condition != 0 {
+			curTime := currentTime{b.clock()}
+			offer.TireYear = curTime.getTireYear(b.avito.TireYear)
+		}
 
-						if len(offer.Images) == 10 {
-							break
-						}
-					}
-				}
-			}
+		catalogModel, _ := p.String("catalog_model")
+		offer.Model = buildOfferModel(catalogModel, b.avitoModels, b.regexpTiresModel)
+	}
 
-			if len(offer.Images) == 0 && avito.AlternativeImageProxy != "" && avito.AlwaysGenerateImage {
-				emptyImg := "05c40c050e1eeef58efb8bcf8e6ce2510b.png"
-				if avito.AlternativeImageRequestMethod == "URL-JPG" {
-					emptyImg = "1149f97a082eb731bab1e4d0bb281be3e8.jpg"
-				}
+	offer.ID = offerID
+	offer.Address = b.avito.Address
 
-				imgURL := strings.TrimSuffix(avito.AlternativeImageProxy, "/") +
-					"/images/" + strings.ToLower(replaceModAutorus(pos.Brand, false)) + "/" + strings.ToLower(replaceModAutorus(pos.Number, true)) +
-					"/full/" + emptyImg
-				imgURL = addImageIncParam(imgURL, params.ImageInc)
+	if len(b.avito.AdditionalAddresses) != 0 {
+		b.avito.AdditionalAddresses = deleteDuplicateAddrs(b.avito.AdditionalAddresses)
+		offer.Addresses = getAddresses(b.avito.AdditionalAddresses)
+	}
 
-				offer.Images = append(offer.Images, xmlImage{imgURL})
-			}
+	if len(b.avito.DisplayAreas) > 0 {
+		displayAreas := make([]string, 0)
+		displayAreas = append(displayAreas, b.avito.DisplayAreas...)
+		offer.DisplayAreas = &displayAreas
+	}
 
-			// Блок для работы с бу товарами
-			if pos.Condition != 0 {
-				offer.Images = make([]xmlImage, 0)
-				for _, img := range pos.UsedImages {
+	offer.ContactPhone = b.avito.ContactPhone
 
-					imgURL := buildImgURLOffer(img, pfx, avito.AlternativeImageProxy, pos.Brand, pos.Number, avito.DisableAlternativeImage, avito.UpdatePhoto, avito.UpdatePhotoCount)
-					imgURL = addImageIncParam(imgURL, params.ImageInc)
-					offer.Images = append(offer.Images, xmlImage{URL: imgURL})
+	offer.ManagerName = b.avito.ManagerName
 
-					if avito.AlternativeImageProxy != "" {
-						break
-					}
+	if pos.isDisks() {
+		offer.RimBrand = pos.Brand
+	} else {
+		offer.Brand = pos.Brand
+	}
 
-					if len(offer.Images) == 10 {
-						break
-					}
-				}
+	b.buildDates(&offer, pos)
+	offer.ListingFee = buildListingFee(b.avito.ListingFee)
+	offer.AdStatus = buildAdStatus(b.avito.AdStatus)
+	if status, ok := b.promotion.status(pos, b.clock()); ok {
+		offer.AdStatus = status
+	}
+	offer.ContactMethod = buildContactMethod(b.avito.ContactMethod)
+	offer.AdType = buildAdType(b.avito.AdType)
+	offer.Condition = buildCondition(b.avito.Condition)
 
-				offer.Condition = params.Localization.WearoutPreOwned
-			}
+	if pos.Condition != 0 {
+		offer.Condition = b.params.Localization.WearoutPreOwned
+	}
+	if !pos.isTires() && !pos.isDisks() {
+		setDonorTags(&offer, usedDonor(pos, p))
+	}
 
-			if len(offer.Images) == 0 && avito.ExcludeOffersWithoutPicture {
-				continue
-			}
+	if pos.Description == "" {
+		if description, ok := p.String("descr"); ok {
+			pos.Description = description
+		}
+	} else {
+		pos.Description = regexpDescription.ReplaceAllString(pos.Description, "")
+	}
 
-			switch avito.FilterOffersPicture {
-			case 1:
-				if len(offer.Images) == 0 {
-					continue
-				}
-			case 2:
-				if len(offer.Images) != 0 {
-					continue
-				}
-			}
+	xmlParams := b.buildXMLParams(pos, props, pns)
 
-			offer.VideoURL = avito.VideoURL
+	// Исключение по описанию проверяется без условий продажи, общих для всех предложений.
+	description := b.buildDescription(pos, props, key, xmlParams, "")
+	if excludeOfferWithDescriptions(b.params.IncludedDescriptions, b.params.ExcludedDescriptions, description, b.regexpsIncludedDescription, b.regexpsExcludedDescription) {
+		return offer, false
+	}
 
-			if pos.Description == "" {
-				if descriptionProp, ok := props["descr"]; ok {
-					if description, ok := descriptionProp.(string); ok {
-						pos.Description = description
-					}
-				}
-			} else {
-				pos.Description = regexpDescription.ReplaceAllString(pos.Description, "")
-			}
+	offer.Description = newCharData(b.buildFinalDescription(pos, props, key, xmlParams))
 
-			var xmlParams []xmlParam
-			if pos.GoodsGroupCode == "" || pos.GoodsGroupCode == "others" {
-				if props["goods_group"] != nil {
-					xmlParams = getXMLParams(props, pns, params.PriorityDescriptionSource)
-				}
-			} else {
-				xmlParams = getXMLParams(props, pns, params.PriorityDescriptionSource)
-			}
+	// Доступность изображений проверяется запросами к хосту, поэтому после исключений по описанию.
+	offer.Images = b.buildImages(pos, p, key)
+	if len(offer.Images) == 0 && b.avito.ExcludeOffersWithoutPicture {
+		return offer, false
+	}
 
-			if pos.GoodsGroupCode == "wipers" || props["goods_group"] == "wipers" {
-				xmlParams = append(xmlParams, addWipersParams(pos.Brand)...)
-			}
+	switch b.avito.FilterOffersPicture {
+	case 1:
+		if len(offer.Images) == 0 {
+			return offer, false
+		}
+	case 2:
+		if len(offer.Images) != 0 {
+			return offer, false
+		}
+	}
 
-			productDetail := buildProductDetail(xmlParams)
+	offer.VideoURL = b.avito.VideoURL
+
+	excludeReqCategoryByBrandForGoodsGroups := map[string]bool{
+		"22":  true,
+		"105": true,
+		"26":  true,
+		"33":  true,
+		"9":   true,
+		"81":  true,
+		"10":  true,
+		"76":  true,
+		"75":  true,
+		"109": true,
+		"106": true,
+		"214": true,
+		"110": true,
+	}
+	ggID := PricegenStorage.GetGoodsGroupsID(props["goods_group"], pos.GoodsGroupCode)
+	//if !excludeReqCategoryByBrandForGoodsGroups[ggID] && pos.ArticlesIsСargo(b.avitoBrandCategoriesTags, b.params.PriorityDescriptionSource, offer.Brand, b.avitoDescrCategoriesTags, b.avitoCategoriesTags, ggID) {
+	//	offer.ProductType = "Для грузовиков и спецтехники"
+	//}
+
+	// Сначала определяем категорию по бренду
+	if !excludeReqCategoryByBrandForGoodsGroups[ggID] {
+		offer.Category, offer.GoodsType, offer.ProductType, offer.SparePartType = pos.buildTagsByBrand(b.avitoBrandCategoriesTags, b.params.PriorityDescriptionSource, offer.Brand)
+	}
 
-			description := buildOfferDescription(pos, productDetail, params.PriorityDescriptionSource, params.Localization, avito.RemoveStatementTypeFromDescr)
-			if excludeOfferWithDescriptions(params.IncludedDescriptions, params.ExcludedDescriptions, description, regexpsIncludedDescription, regexpsExcludedDescription) {
-				continue
-			}
+	// Определяем категорию по goodsGroup если не заполнили по бренду
+	var sparePartType2 string
+	if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" {
+		offer.ProductType = b.avitoCategoriesTags[ggID].ProductType
+		offer.SparePartType = b.avitoCategoriesTags[ggID].SparePartType
+		offer.Category = b.avitoCategoriesTags[ggID].Category
+		offer.GoodsType = b.avitoCategoriesTags[ggID].GoodsType
+		sparePartType2 = b.avitoCategoriesTags[ggID].SparePartType2
+	}
 
-			description = buildFinalOfferDescription(description, avito.SalesConditions)
-			offer.Description = newCharData(description)
-
-			excludeReqCategoryByBrandForGoodsGroups := map[string]bool{
-				"22":  true,
-				"105": true,
-				"26":  true,
-				"33":  true,
-				"9":   true,
-				"81":  true,
-				"10":  true,
-				"76":  true,
-				"75":  true,
-				"109": true,
-				"106": true,
-				"214": true,
-				"110": true,
-			}
-			ggID := PricegenStorage.GetGoodsGroupsID(props["goods_group"], pos.GoodsGroupCode)
-			//if !excludeReqCategoryByBrandForGoodsGroups[ggID] && pos.ArticlesIsСargo(avitoBrandCategoriesTags, params.PriorityDescriptionSource, offer.Brand, avitoDescrCategoriesTags, avitoCategoriesTags, ggID) {
-			//	offer.ProductType = "Для грузовиков и спецтехники"
-			//}
-
-			// Сначала определяем категорию по бренду
-			if !excludeReqCategoryByBrandForGoodsGroups[ggID] {
-				offer.Category, offer.GoodsType, offer.ProductType, offer.SparePartType = pos.buildTagsByBrand(avitoBrandCategoriesTags, params.PriorityDescriptionSource, offer.Brand)
-			}
+	// Определяем категорию по описанию если не заполнили по бренду и по goodsGroup
+	if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" && sparePartType2 == "" {
+		offer.Category, offer.GoodsType, offer.ProductType, offer.SparePartType, props["goods_group"], sparePartType2 = pos.buildTagsByDescription(b.avitoCategoriesTags, b.avitoDescrCategoriesTags, b.params.PriorityDescriptionSource)
+	}
 
-			// Определяем категорию по goodsGroup если не заполнили по бренду
-			var sparePartType2 string
-			if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" {
-				offer.ProductType = avitoCategoriesTags[ggID].ProductType
-				offer.SparePartType = avitoCategoriesTags[ggID].SparePartType
-				offer.Category = avitoCategoriesTags[ggID].Category
-				offer.GoodsType = avitoCategoriesTags[ggID].GoodsType
-				sparePartType2 = avitoCategoriesTags[ggID].SparePartType2
-			}
+	if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" && sparePartType2 == "" {
+		ggID := "1"
+		offer.ProductType = b.avitoCategoriesTags[ggID].ProductType
+		offer.SparePartType = b.avitoCategoriesTags[ggID].SparePartType
+		offer.Category = b.avitoCategoriesTags[ggID].Category
+		offer.GoodsType = b.avitoCategoriesTags[ggID].GoodsType
+		sparePartType2 = b.avitoCategoriesTags[ggID].SparePartType2
+	}
 
-			// Определяем категорию по описанию если не заполнили по бренду и по goodsGroup
-			if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" && sparePartType2 == "" {
-				offer.Category, offer.GoodsType, offer.ProductType, offer.SparePartType, props["goods_group"], sparePartType2 = pos.buildTagsByDescription(avitoCategoriesTags, avitoDescrCategoriesTags, params.PriorityDescriptionSource)
-			}
+	if offer.ProductType == "Для грузовиков и спецтехники" {
+		offer.SparePartType, offer.TechnicSparePartType = pos.buildTagsByTruckDescription(b.avitoTruckDescrCategoriesTags, b.params.PriorityDescriptionSource)
 
-			if offer.ProductType == "" && offer.SparePartType == "" && offer.Category == "" && offer.GoodsType == "" && sparePartType2 == "" {
-				ggID := "1"
-				offer.ProductType = avitoCategoriesTags[ggID].ProductType
-				offer.SparePartType = avitoCategoriesTags[ggID].SparePartType
-				offer.Category = avitoCategoriesTags[ggID].Category
-				offer.GoodsType = avitoCategoriesTags[ggID].GoodsType
-				sparePartType2 = avitoCategoriesTags[ggID].SparePartType2
-			}
+		if offer.SparePartType == "" && offer.TechnicSparePartType == "" {
+			offer.SparePartType = "Трансмиссия"
+			offer.TechnicSparePartType = "Детали КПП"
+		}
+	}
 
-			if offer.ProductType == "Для грузовиков и спецтехники" {
-				offer.SparePartType, offer.TechnicSparePartType = pos.buildTagsByTruckDescription(avitoTruckDescrCategoriesTags, params.PriorityDescriptionSource)
+	if offer.ProductType == "Трансмиссионные масла" {
+		applicabilityProp := getApplicabilityProp(p)
+		if applicabilityProp == "ГУР" {
+			offer.ProductType = "Гидравлические жидкости"
+		}
+	}
 
-				if offer.SparePartType == "" && offer.TechnicSparePartType == "" {
-					offer.SparePartType = "Трансмиссия"
-					offer.TechnicSparePartType = "Детали КПП"
-				}
-			}
+	if offer.GoodsType == "Аксессуары" {
+		offer.AccessoryType = offer.SparePartType
+		offer.SparePartType = ""
+	}
 
-			if offer.ProductType == "Трансмиссионные масла" {
-				applicabilityProp := getApplicabilityProp(props)
-				if applicabilityProp == "ГУР" {
-					offer.ProductType = "Гидравлические жидкости"
-				}
-			}
+	if offer.GoodsType == "Противоугонные устройства" {
+		offer.DeviceType = offer.ProductType
+		offer.ProductType = ""
+	}
 
-			if offer.GoodsType == "Аксессуары" {
-				offer.AccessoryType = offer.SparePartType
-				offer.SparePartType = ""
-			}
+	if offer.AccessoryType == "Дефлекторы" {
+		offer.InstallationLocation = "Окна"
+	}
 
-			if offer.GoodsType == "Противоугонные устройства" {
-				offer.DeviceType = offer.ProductType
-				offer.ProductType = ""
-			}
+	// Построение специфических тегов для определенных goodsGroups
+	if pos.GoodsGroupCode == "bicycles" || props["goods_group"] == "bicycles" {
+		bicycle := buildBicycleTags(p, b.bicycleTypes)
+		offer.VehicleType = bicycle.VehicleType
+		offer.WheelDiameter = bicycle.WheelDiameter
+		offer.FrameSize = bicycle.FrameSize
+		offer.Speeds = bicycle.Speeds
+	}
 
-			if offer.AccessoryType == "Дефлекторы" {
-				offer.InstallationLocation = "Окна"
-			}
+	if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" {
+		if abcpATF, ok := p.String("atf_spec"); ok {
+			offer.ATF = getATF(b.avitoAtfSpec, abcpATF)
+		}
+	}
 
-			// Построение специфических тегов для определенных goodsGroups
-			if pos.GoodsGroupCode == "bicycles" || props["goods_group"] == "bicycles" {
-				offer.VehicleType = buildVehicleType(props)
-			}
+	if pos.GoodsGroupCode == "compressor_oils" || props["goods_group"] == "compressor_oils" {
+		if volume, ok := p.Raw("liquid_volume"); ok {
+			offer.Volume = volumeTag.format(volume)
+		}
+	}
 
-			if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" {
-				if _, ok := props["atf_spec"]; ok {
-					abcpATF := getAbcpATFByType(props["atf_spec"])
-					offer.ATF = getATF(avitoAtfSpec, abcpATF)
-				}
-			}
+	if pos.GoodsGroupCode == "oils" || props["goods_group"] == "oils" {
+		offer = setOilsTags(offer, p, pos.Number)
+	}
 
-			if pos.GoodsGroupCode == "compressor_oils" || props["goods_group"] == "compressor_oils" {
-				if _, ok := props["liquid_volume"]; ok {
-					offer.Volume = replaceSeparatorToComma(props["liquid_volume"].(string)) + " л"
-				}
-			}
+	if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" {
+		offer = setGearOilsTags(offer, p, pos.Number)
+	}
 
-			if pos.GoodsGroupCode == "oils" || props["goods_group"] == "oils" {
-				offer = setOilsTags(offer, props, pos.Number)
-			}
+	if pos.GoodsGroupCode == "brake_fluids" || props["goods_group"] == "brake_fluids" {
+		offer = setBrakeFluidsTags(offer, p, pos.Number)
+	}
 
-			if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" {
-				offer = setGearOilsTags(offer, props, pos.Number)
-			}
+	if pos.GoodsGroupCode == "coolant" || props["goods_group"] == "coolant" {
+		offer = setCoolantTags(offer, p, pos.Number)
+	}
 
-			if pos.GoodsGroupCode == "brake_fluids" || props["goods_group"] == "brake_fluids" {
-				offer = setBrakeFluidsTags(offer, props, pos.Number)
-			}
+	if pos.GoodsGroupCode == "batteries" || props["goods_group"] == "batteries" {
+		offer = setBatteriesTags(offer, p)
+	}
 
-			if pos.GoodsGroupCode == "coolant" || props["goods_group"] == "coolant" {
-				offer = setCoolantTags(offer, props, pos.Number)
-			}
+	if pos.GoodsGroupCode == "wipers" || props["goods_group"] == "wipers" {
+		offer = setWipersTags(offer, p, pos.Brand, b.mountingTypes)
+	}
 
-			if pos.GoodsGroupCode == "batteries" || props["goods_group"] == "batteries" {
-				offer = setBatteriesTags(offer, props)
-			}
+	if offer.SparePartType == "Кузов" {
+		offer.BodySparePartType = sparePartType2
+	}
 
-			if pos.GoodsGroupCode == "wipers" || props["goods_group"] == "wipers" {
-				offer = setWipersTags(offer, props, pos.Brand)
-			}
+	if offer.SparePartType == "Двигатель" {
+		offer.EngineSparePartType = sparePartType2
+	}
 
-			if offer.SparePartType == "Кузов" {
-				offer.BodySparePartType = sparePartType2
-			}
+	if offer.GoodsType == "Багажники и фаркопы" {
+		offer.TrunkType = sparePartType2
+	}
 
-			if offer.SparePartType == "Двигатель" {
-				offer.EngineSparePartType = sparePartType2
-			}
+	if offer.SparePartType == "Трансмиссия и привод" {
+		offer.TransmissionSparePartType = sparePartType2
+	}
 
-			if offer.GoodsType == "Багажники и фаркопы" {
-				offer.TrunkType = sparePartType2
-			}
+	title, ok := buildOfferName(pos, props, b.titles, b.titleShortener, b.params.Localization, b.params.PriorityDescriptionSource)
+	if !ok {
+		b.report.addLongTitle(key, title)
+	}
+	offer.Title = title
 
-			if offer.SparePartType == "Трансмиссия и привод" {
-				offer.TransmissionSparePartType = sparePartType2
-			}
+	if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" ||
+		pos.GoodsGroupCode == "oils" || props["goods_group"] == "oils" {
+		apiSpec, _ := p.Strings("api_spec")
+		offer.API = getAPISpec(apiSpec)
+	}
 
-			offer.Title = buildOfferName(pos, props, params.Localization, params.PriorityDescriptionSource)
+	if pos.isTires() {
+		offer.Quantity = getQuantity(b.avito.TiresQuantityType, b.avito.TiresQuantity, pos.Packing)
+		price = price * offer.Quantity
+	}
 
-			if pos.GoodsGroupCode == "gear_oils" || props["goods_group"] == "gear_oils" ||
-				pos.GoodsGroupCode == "oils" || props["goods_group"] == "oils" {
-				offer.API = getAPISpec(props["api_spec"])
-			}
+	offer.Availability = buildAvailability(b.avito.Availability, pos.DeadLine)
 
-			if pos.isTires() {
-				offer.Quantity = getQuantity(avito.TiresQuantityType, avito.TiresQuantity, pos.Packing)
-				price = price * offer.Quantity
-			}
+	if props["goods_group"] == "wheel_covers" {
+		if diameter, ok := p.String("diameter"); ok {
+			offer.RimDiameter = diameter
+		}
+	}
 
-			offer.Availability = buildAvailability(avito.Availability, pos.DeadLine)
+	if pos.isTires() || pos.isDisks() {
+		if axle, ok := p.String("axle"); ok {
+			offer.WheelAxle = axle
+		}
 
-			if props["goods_group"] == "wheel_covers" {
-				if v, ok := props["diameter"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimDiameter = str
-					}
-				}
+		if pos.GoodsGroupCode == "tires" {
+			if season, ok := p.String("season"); ok {
+				offer.TireType = getTyreType(season)
 			}
+		}
 
-			if pos.isTires() || pos.isDisks() {
-				if v, ok := props["axle"]; ok {
-					if str, ok := v.(string); ok {
-						offer.WheelAxle = str
-					}
-				}
-
-				if pos.GoodsGroupCode == "tires" {
-					if v, ok := props["season"]; ok {
-						if season, ok := v.(string); ok {
-							offer.TireType = getTyreType(season)
-						}
-					}
-				}
-
-				if pos.GoodsGroupCode == "truck_tires" {
-					offer.TireType = "Всесезонные"
-				}
+		if pos.GoodsGroupCode == "truck_tires" {
+			offer.TireType = "Всесезонные"
+		}
 
-				if v, ok := props["diameter"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimDiameter = str
-					}
-				}
+		if diameter, ok := p.String("diameter"); ok {
+			offer.RimDiameter = diameter
+		}
 
-				if v, ok := props["axle"]; ok {
-					if str, ok := v.(string); ok {
-						offer.WheelAxle = str
-					}
-				}
+		if axle, ok := p.String("axle"); ok {
+			offer.WheelAxle = axle
+		}
 
-				if v, ok := props["disk_type"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimType = getRimType(str)
-					}
-				}
+		if diskType, ok := p.String("disk_type"); ok {
+			offer.RimType = getRimType(diskType)
+		}
 
-				if v, ok := props["holes"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimBolts = str
-					}
-				}
+		if holes, ok := p.String("holes"); ok {
+			offer.RimBolts = holes
+		}
 
-				if v, ok := props["pcd"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimBoltsDiameter = str
-					}
-				}
+		if pcd, ok := p.String("pcd"); ok {
+			offer.RimBoltsDiameter = pcd
+		}
 
-				if v, ok := props["et"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimOffset = str
-					}
-				}
-			}
+		if et, ok := p.String("et"); ok {
+			offer.RimOffset = et
+		}
+	}
 
-			if pos.isTires() {
-				if v, ok := props["width"]; ok {
-					if str, ok := v.(string); ok {
-						offer.TireSectionWidth = str
-					}
-				}
-				if v, ok := props["height"]; ok {
-					if str, ok := v.(string); ok {
-						offer.TireAspectRatio = str
-					}
-				}
+	if pos.isTires() {
+		if width, ok := p.String("width"); ok {
+			offer.TireSectionWidth = width
+		}
+		if height, ok := p.String("height"); ok {
+			offer.TireAspectRatio = height
+		}
 
-				if offer.TireAspectRatio == "0" || offer.TireSectionWidth == "0" {
-					continue
-				}
-			}
+		if offer.TireAspectRatio == "0" || offer.TireSectionWidth == "0" {
+			return offer, false
+		}
+	}
 
-			if pos.isDisks() {
-				if v, ok := props["width"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimWidth = str
-					}
-				}
-				if v, ok := props["hub_diameter"]; ok {
-					if str, ok := v.(string); ok {
-						offer.RimDia = str
-					}
-				}
-				if offer.RimWidth == "0" {
-					continue
-				}
-			}
+	if pos.isDisks() {
+		if width, ok := p.String("width"); ok {
+			offer.RimWidth = width
+		}
+		if hubDiameter, ok := p.String("hub_diameter"); ok {
+			offer.RimDia = hubDiameter
+		}
+		if offer.RimWidth == "0" {
+			return offer, false
+		}
+	}
 
-			if !pos.isTires() && !pos.isDisks() && !pos.isOils() && (pos.GoodsGroupCode != "bicycles" || props["goods_group"] != "bicycles") {
-				offer.OEM = pos.Number
-			}
+	if !pos.isTires() && !pos.isDisks() && !pos.isOils() && (pos.GoodsGroupCode != "bicycles" || props["goods_group"] != "bicycles") {
+		offer.OEM = pos.Number
+	}
 
-			if pos.isOils() {
+	if pos.isOils() {
 
-				var ok bool
+		// Спецификации берутся из исходных свойств, без перевода значений.
+		raw := newPropReader(b.properties[key], key, b.report)
 
-				if pos.GoodsGroupCode == "coolant" {
-					if _, ok = properties[key]["coolant_oem_spec"]; ok {
-						options := getOEMOil(properties[key]["coolant_oem_spec"], avitoOemSpec)
-						if len(options) > 0 {
-							offer.OEMOil = &options
-						}
-					}
-				} else {
-					if _, ok = properties[key]["oem_spec"]; ok {
-						options := getOEMOil(properties[key]["oem_spec"], avitoOemSpec)
-						if len(options) > 0 {
-							offer.OEMOil = &options
-						}
-					}
-				}
+		oemProp := "oem_spec"
+		if pos.GoodsGroupCode == "coolant" {
+			oemProp = "coolant_oem_spec"
+		}
 
-				if pos.GoodsGroupCode == "coolant" {
-					if _, ok = properties[key]["coolant_astm_spec"]; ok {
-						astm, ok := properties[key]["coolant_astm_spec"].([]any)
-						if ok && len(astm) > 0 {
-							var aa []string
-							for _, v := range astm {
-								aa = append(aa, v.(string))
-							}
-							offer.ASTM = &aa
-						}
-					}
-				}
+		if abcpOptions, ok := raw.Strings(oemProp); ok {
+			options := getOEMOil(abcpOptions, b.avitoOemSpec)
+			if len(options) > 0 {
+				offer.OEMOil = &options
 			}
+		}
 
-			offer.InternetCalls = getInternetCalls(avito.InternetCalls)
-
-			if len(avito.CallsDevices) > 0 {
-				dd := make([]string, 0)
-				dd = append(dd, avito.CallsDevices...)
-				offer.CallsDevices = &dd
+		if pos.GoodsGroupCode == "coolant" {
+			if astm, ok := raw.Strings("coolant_astm_spec"); ok {
+				offer.ASTM = &astm
 			}
+		}
+	}
 
-			if len(avito.DeliveryFromPrices) > 0 {
-				for _, delivery := range avito.DeliveryFromPrices {
-					// если цена товара попадает в диапазон между MinPrice и MaxPrice
-					// или больше MinPrice, когда MaxPrice не указан,
-					// создаём тег Delivery с перечнем возможных доставок
-					if price >= int(delivery.MinPrice) &&
-						(price < int(delivery.MaxPrice) || delivery.MaxPrice == 0) {
+	offer.InternetCalls = getInternetCalls(b.avito.InternetCalls)
 
-						offer.Delivery = &delivery.DeliveryTypes
-						break
-					}
-				}
-			}
-
-			if !avito.HidePriceTag {
-				offer.Price = price
-			}
+	if len(b.avito.CallsDevices) > 0 {
+		dd := make([]string, 0)
+		dd = append(dd, b.avito.CallsDevices...)
+		offer.CallsDevices = &dd
+	}
 
-			offers[offer.ID] = offer
-		}
+	if len(b.avito.DeliveryFromPrices) > 0 {
+		for _, delivery := range b.avito.DeliveryFromPrices {
+			// если цена товара попадает в диапазон между MinPrice и MaxPrice
+			// или больше MinPrice, когда MaxPrice не указан,
+			// создаём тег Delivery с перечнем возможных доставок
+			if price >= int(delivery.MinPrice) &&
+				(price < int(delivery.MaxPrice) || delivery.MaxPrice == 0) {
 
-		var content []xmlOffer
-		for _, v := range offers {
-			content = append(content, v)
+				offer.Delivery = &delivery.DeliveryTypes
+				break
+			}
 		}
+	}
 
-		offers = make(map[string]xmlOffer)
-		s.count += len(content)
-		s.output <- content
+	if !b.avito.HidePriceTag {
+		offer.Price = price
 	}
 
-	log.Printf("Задача %d: определены офферы для %d позиций", s.id, s.count)
+	return offer, true
 }
 
 func excludeOfferWithDescriptions(includedDesc, excludedDesc []string, desc string, regexpsIncludedDesc, regexpsExcludedDesc []*regexp.Regexp) bool {
@@ -903,58 +969,161 @@ func writeOffersStockInFile(inputchan <-chan []xmlOfferStock, fileName string) (
 	return fileName, count, nil
 }
 
-func getXMLParams(props Properties, pns map[string]string, priorityDescSource int) []xmlParam {
-
-	requiredPropsByGoodsGroup := map[string][]string{
-		"bicycles":        {"age", "type"},
-		"gear_oils":       {"atf_spec", "viscosity", "liquid_volume", "api_spec"},
-		"compressor_oils": {"liquid_volume"},
-		"oils":            {"viscosity", "liquid_volume", "acea_spec", "api_spec"},
-		"brake_fluids":    {"dot_spec", "liquid_volume"},
-		"coolant":         {"coolant_color", "liquid_volume"},
-		"batteries":       {"voltage", "capacity", "cca", "polarity", "length", "width", "height"},
-		"wheel_covers":    {"diameter"},
-		"moto_tires":      {"axle", "diameter", "disk_type", "holes", "pcd", "et", "width", "height"},
-		"truck_tires":     {"axle", "season", "diameter", "disk_type", "holes", "pcd", "et", "width", "height"},
-		"tires":           {"axle", "season", "diameter", "disk_type", "holes", "pcd", "et", "width", "height"},
-		"disks":           {"axle", "season", "diameter", "disk_type", "holes", "pcd", "et", "width", "hub_diameter"},
-		"wipers":          {"pack_count", "connector", "construction", "length1", "length2"},
-	}
-
-	group := ""
-	requiredProps := make([]string, 0)
-	propsCopy := copyMap(props)
-	if gg := propsCopy["goods_group"]; gg != nil {
-		group = gg.(string)
-		requiredProps = requiredPropsByGoodsGroup[group]
-	}
-
-	if len(requiredProps) != 0 && (priorityDescSource == 0 || priorityDescSource == 1 || priorityDescSource == 3 || priorityDescSource == 4) {
-		for currentProp := range propsCopy {
-
-			for i, requiredProp := range requiredProps {
-				if currentProp == requiredProp {
-					break
-				}
+// buildImages формирует изображения предложения по политике выбора изображений клиента.
+// Изображения отбираются без повторов, проверяется их доступность, и только затем
+// применяется ограничение количества, чтобы недоступные изображения не занимали места доступных.
+// Если изображений нет, при AlwaysGenerateImage добавляется заглушка клиента или поставщика изображений.
+func (b *offerBuilder) buildImages(pos position, p propReader, key string) []xmlImage {
 
-				if i == len(requiredProps)-1 {
-					delete(propsCopy, currentProp)
-				}
-			}
+	var images []xmlImage
+	for _, name := range b.imagePolicy.selectImages(pos, p) {
+		u, err := b.imageProvider.ImageURL(ImageRef{Name: name, Brand: pos.Brand, Number: pos.Number})
+		if err != nil {
+			b.report.addMissingImage(key, name)
+			continue
+		}
+		b.imageVersions.apply(u, name)
+		images = append(images, xmlImage{URL: b.imageURL(u)})
+	}
+	images = b.imagePolicy.limit(b.imageChecker.filter(images, key, b.report))
+
+	if len(images) == 0 && !b.imagePolicy.replacesCatalog(pos) && b.avito.AlwaysGenerateImage {
+		group := pos.GoodsGroupCode
+		if group == "" {
+			group, _ = p.String("goods_group")
+		}
+		if u, ok := b.placeholderURL(pos, group); ok {
+			images = append(images, xmlImage{URL: b.imageURL(u)})
 		}
 	}
 
-	out := make([]xmlParam, 0)
-	for name, value := range propsCopy {
+	return images
+}
+
+// imageURL возвращает ссылку на изображение с параметром ImageInc.
+func (b *offerBuilder) imageURL(u *url.URL) string {
+
+	if b.params.ImageInc > 0 {
+		appendImageQuery(u, strconv.Itoa(b.params.ImageInc))
+	}
+
+	return u.String()
+}
+
+// offerID возвращает идентификатор предложения позиции pos по шаблону клиента или режиму AvitoOfferID.
+// Закреплённый в хранилище идентификатор позиции имеет приоритет.
+func (b *offerBuilder) offerID(pos position) string {
+
+	id := ""
+	if b.offerIDTemplate != nil {
+		id = b.offerIDTemplate.build(pos)
+	} else {
+		id = buildOfferID(pos, b.avito.AvitoOfferID)
+	}
+
+	return b.offerIDStore.Resolve(offerKey(pos), id)
+}
+
+// buildXMLParams формирует список параметров описания позиции pos.
+func (b *offerBuilder) buildXMLParams(pos position, props Properties, pns map[string]string) []xmlParam {
+
+	var xmlParams []xmlParam
+	propsGroup, _ := props["goods_group"].(string)
+	if pos.GoodsGroupCode == "" || pos.GoodsGroupCode == "others" {
+		if props["goods_group"] != nil {
+			xmlParams = getXMLParams(props, pns, newParamsOrder(b.goodsGroupPack, propsGroup),
+				b.descriptionProps.filter(propsGroup, b.params.PriorityDescriptionSource), b.mountingTypes)
+		}
+	} else {
+		xmlParams = getXMLParams(props, pns, newParamsOrder(b.goodsGroupPack, pos.GoodsGroupCode),
+			b.descriptionProps.filter(propsGroup, b.params.PriorityDescriptionSource), b.mountingTypes)
+	}
+
+	if pos.GoodsGroupCode == "wipers" || propsGroup == "wipers" {
+		xmlParams = append(xmlParams, addWipersParams(pos.Brand)...)
+	}
+
+	return xmlParams
+}
+
+// buildDescription формирует описание позиции pos по шаблону описания клиента.
+func (b *offerBuilder) buildDescription(pos position, props Properties, key string, xmlParams []xmlParam, salesConditions string) string {
+
+	data := b.newDescriptionData(pos, props, xmlParams)
+	data.SalesConditions = salesConditions
+
+	return b.executeDescription(data, key)
+}
+
+// buildFinalDescription формирует значение тега <Description> позиции pos.
+// Если описание длиннее descCharacterLimit, разделы описания сокращаются
+// в порядке descriptionShrinkOrder, и только затем обрезается итоговый текст.
+func (b *offerBuilder) buildFinalDescription(pos position, props Properties, key string, xmlParams []xmlParam) string {
+
+	data := b.newDescriptionData(pos, props, xmlParams)
+	data.SalesConditions = b.avito.SalesConditions
+
+	render := func() string {
+		return replaceDescriptionNewlines(sanitizeDescription(b.executeDescription(data, key)))
+	}
+
+	d := render()
+	for _, section := range descriptionShrinkOrder {
+		for excess := descriptionExcess(d); excess > 0 && shrinkDescriptionSection(&data, section, excess); excess = descriptionExcess(d) {
+			d = render()
+		}
+	}
+
+	return buildFinalOfferDescription(d)
+}
+
+// newDescriptionData формирует данные шаблона описания позиции pos с данными автомобиля-донора.
+func (b *offerBuilder) newDescriptionData(pos position, props Properties, xmlParams []xmlParam) descriptionData {
+
+	data := newDescriptionData(pos, props, xmlParams, b.params.PriorityDescriptionSource,
+		b.params.Localization, b.avito.RemoveStatementTypeFromDescr)
+	p := newPropReader(props, strings.ToUpper(pos.Brand+"|"+pos.Number), b.report)
+	data.Donor = b.donorLabels.params(usedDonor(pos, p))
+	data.DonorDetail = b.donorLabels.detail(data.Donor)
+
+	return data
+}
+
+// executeDescription выполняет шаблон описания клиента.
+// Если шаблон клиента не удалось выполнить, используется встроенный шаблон.
+func (b *offerBuilder) executeDescription(data descriptionData, key string) string {
+
+	d, err := b.description.execute(data)
+	if err != nil {
+		b.report.addTemplateError("description", key, err)
+		d, _ = b.defaultDescription.execute(data)
+	}
+
+	return d
+}
+
+// getXMLParams формирует параметры описания из свойств товара в порядке order.
+func getXMLParams(props Properties, pns map[string]string, order paramsOrder, filter propsFilter, mountingTypes *valueDict) []xmlParam {
+
+	group, _ := props["goods_group"].(string)
+
+	out := make([]namedParam, 0)
+	for name, value := range props {
+
+		if !filter.allowed(name) {
+			continue
+		}
 
 		translated, ok := pns[name]
 		if !ok {
 			continue
 		}
 
-		_, isSliceAny := value.([]any)
-		if group == "wipers" && name == "connector" && isSliceAny {
-			value = value.([]any)[0]
+		if sliceAny, ok := value.([]any); ok && group == "wipers" && name == "connector" {
+			if len(sliceAny) == 0 {
+				continue
+			}
+			value = sliceAny[0]
 		}
 
 		if sliceVal := reflect.ValueOf(value); sliceVal.Kind() == reflect.Slice {
@@ -965,24 +1134,28 @@ func getXMLParams(props Properties, pns map[string]string, priorityDescSource in
 				}
 			}
 
-			out = append(out, xmlParam{
+			out = append(out, namedParam{name, xmlParam{
 				Name:    translated,
 				Content: strings.Join(slice, "/"),
-			})
+			}})
 
 			continue
 		}
 
 		content := ""
+		if m, ok := paramMeasures[name]; ok {
+			if _, ok := parseQuantity(value); ok {
+				value = m.format(value)
+			}
+		}
+
 		switch v := value.(type) {
 		case float64:
-			content = strconv.FormatFloat(v, 'f', 2, 64)
+			content = formatNumber(v, 2)
 		case string:
 			switch name {
-			case "liquid_volume":
-				content = replaceSeparatorToComma(v)
 			case "connector":
-				content = getMountingType(value)
+				content = getMountingType(v, mountingTypes)
 			case "construction":
 				caser := cases.Title(language.Russian)
 				content = caser.String(v)
@@ -997,32 +1170,14 @@ func getXMLParams(props Properties, pns map[string]string, priorityDescSource in
 		}
 
 		if content != "" {
-			out = append(out, xmlParam{
+			out = append(out, namedParam{name, xmlParam{
 				Name:    translated,
 				Content: content,
-			})
+			}})
 		}
 	}
 
-	return out
-}
-
-func addFileNamePostfix(name, pfx string) string {
-
-	ss := strings.Split(name, ".")
-	l := len(ss)
-
-	if l == 0 {
-		return pfx
-	}
-
-	if l == 1 {
-		return name + pfx
-	}
-
-	ss[l-2] = ss[l-2] + pfx
-
-	return strings.Join(ss, ".")
+	return sortParams(out, order)
 }
 
 func (pos *position) ArticlesIsСargo(brands map[string]AvitoCategoriesTagsStruct, priorityDescriptionSource int, posBrand string, descrCategories, avitoCategoriesTags map[string]AvitoCategoriesTagsStruct, ggID string) bool {
@@ -178,12 +178,12 @@ func TestProcessXML(t *testing.T) {
 				TechnicSparePartType: "",
 				Category:             "",
 				GoodsType:            "",
-				Volume:               "4,0 л",
+				Volume:               "4 л",
 				SAE:                  "5W-30",
 				ACEA:                 "A3/B4",
 				API:                  apiTagStructSlise{Option: []string{"SN", "CF"}},
 				Description: charData{[]byte("<![CDATA[Бренд: BRAND1, артикул: NUM1, Oil for test. <br/><ul>" +
-					"<li>SAE: 5W-30.</li> <li>Volume: 4,0.</li> <li>ACEA: A3/B4.</li> <li>API: SN/CF.</li></ul> ]]>")},
+					"<li>SAE: 5W-30.</li> <li>Volume: 4 л.</li> <li>ACEA: A3/B4.</li> <li>API: SN/CF.</li></ul> ]]>")},
 				Price:         1500,
 				Availability:  "В наличии",
 				VendorCode:    "NUM1",
@@ -268,10 +268,10 @@ func TestProcessXML(t *testing.T) {
 				SparePartType: "Тормозная система",
 				Category:      "Тормозные жидкости",
 				GoodsType:     "Жидкости",
-				Volume:        "1,0 л",
+				Volume:        "1 л",
 				DOT:           "DOT4",
-				Description: charData{[]byte("<![CDATA[Бренд: BRAND2, артикул: NUM2, Brake for test. <br/><ul>DOT: DOT4." +
-					" Volume: 1,0 л. Description: Brake for test.</li> </ul> ]]>")},
+				Description: charData{[]byte("<![CDATA[Бренд: BRAND2, артикул: NUM2, Description. <br/><ul>" +
+					"<li>DOT: DOT4.</li> <li>Volume: 1 л.</li></ul> ]]>")},
 				Price:         500,
 				Availability:  "В наличии",
 				VendorCode:    "NUM2",
@@ -322,15 +322,6 @@ func TestProcessXML(t *testing.T) {
 				gotOffer.DateEnd = ""
 				wantOffer.DateEnd = ""
 
-				// Only check that Description (which is an XML CDATA) contains the expected substring.
-				//if string(wantOffer.Description.Text) != string(charData{}.Text) && !strings.Contains(string(gotOffer.Description.Text), string(wantOffer.Description.Text)) {
-				//	t.Errorf("[case %v] got.Description = %q, want substring %q", tt.name, string(gotOffer.Description.Text), string(wantOffer.Description.Text))
-				//}
-
-				// Ignore Description because auxiliary parameters adds from map and their order can be changed.
-				gotOffer.Description = charData{}
-				wantOffer.Description = charData{}
-
 				if diff := cmp.Diff(wantOffer, gotOffer); diff != "" {
 					t.Errorf("[case %v] checking offer struct returned unexpected diff (-want +got):\n%s", tt.name, diff)
 				}
@@ -511,7 +502,7 @@ func TestGetXMLParams(t *testing.T) {
 			},
 			priorityDescSource: 0,
 			want: []xmlParam{
-				{Name: "Объем", Content: "1.50"},
+				{Name: "Объем", Content: "1,5 л"},
 			},
 		},
 		{
@@ -554,7 +545,21 @@ func TestGetXMLParams(t *testing.T) {
 			},
 			priorityDescSource: 0,
 			want: []xmlParam{
-				{Name: "Объем", Content: "1,5"},
+				{Name: "Объем", Content: "1,5 л"},
+			},
+		},
+		{
+			name: "Liquid volume in milliliters",
+			props: Properties{
+				"goods_group":   "oils",
+				"liquid_volume": "500 мл",
+			},
+			pns: map[string]string{
+				"liquid_volume": "Объем",
+			},
+			priorityDescSource: 0,
+			want: []xmlParam{
+				{Name: "Объем", Content: "0,5 л"},
 			},
 		},
 		{
@@ -615,6 +620,49 @@ func TestGetXMLParams(t *testing.T) {
 				{Name: "Конструкция", Content: "Большая"},
 			},
 		},
+		{
+			name: "Group display order",
+			props: Properties{
+				"goods_group":   "oils",
+				"api_spec":      []any{"SN", "CF"},
+				"liquid_volume": 4.0,
+				"acea_spec":     "A3/B4",
+				"viscosity":     "5W-30",
+			},
+			pns: map[string]string{
+				"viscosity":     "SAE",
+				"liquid_volume": "Volume",
+				"acea_spec":     "ACEA",
+				"api_spec":      "API",
+			},
+			priorityDescSource: 0,
+			want: []xmlParam{
+				{Name: "SAE", Content: "5W-30"},
+				{Name: "Volume", Content: "4 л"},
+				{Name: "ACEA", Content: "A3/B4"},
+				{Name: "API", Content: "SN/CF"},
+			},
+		},
+		{
+			name: "Alphabetical order without group order",
+			props: Properties{
+				"goods_group": "filters",
+				"width":       "10",
+				"height":      "20",
+				"material":    "бумага",
+			},
+			pns: map[string]string{
+				"width":    "Ширина",
+				"height":   "Высота",
+				"material": "Материал",
+			},
+			priorityDescSource: 2,
+			want: []xmlParam{
+				{Name: "Высота", Content: "20"},
+				{Name: "Материал", Content: "бумага"},
+				{Name: "Ширина", Content: "10"},
+			},
+		},
 		{
 			name: "String property with empty translation",
 			props: Properties{
@@ -631,7 +679,9 @@ func TestGetXMLParams(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getXMLParams(tt.props, tt.pns, tt.priorityDescSource)
+			group := getString(tt.props["goods_group"])
+			got := getXMLParams(tt.props, tt.pns, newParamsOrder(nil, group),
+				newDescriptionProps(nil).filter(group, tt.priorityDescSource), newMountingTypeDict(nil))
 			if !cmp.Equal(got, tt.want) {
 				t.Errorf("getXMLParams() = %v, want %v", got, tt.want)
 			}
@@ -1,10 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"crypto/md5"
-	"fmt"
-	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,7 +17,9 @@ type currentTime struct {
 }
 
 // buildOfferName формирует содержимое тега <Title>.
-func buildOfferName(pos position, props map[string]any, localization Localization, priorityDescriptionSource int) string {
+// Возвращает false, если при сокращении до titleRuneLimit символов пришлось обрезать артикул.
+func buildOfferName(pos position, props map[string]any, titles *titleTemplates, shortener *titleShortener,
+	localization Localization, priorityDescriptionSource int) (string, bool) {
 
 	// Заменяем описание позиции описанием сформированным на этапе datacollector для следующих приоритетных источников описания:
 	// 1. "Справочник товаров + Прайс-лист + параметры товара" (priorityDescriptionSource = 5)
@@ -34,7 +32,7 @@ func buildOfferName(pos position, props map[string]any, localization Localizatio
 
 	var offerName string
 	if pos.DescriptionSource == 0 {
-		offerName = getOfferNameByCategory(pos, props)
+		offerName = titles.build(pos, props)
 	} else {
 		offerName = pos.Description
 	}
@@ -45,49 +43,16 @@ func buildOfferName(pos position, props map[string]any, localization Localizatio
 		offerName = localization.WearoutPreOwned + " " + offerName
 	}
 
-	nameRunes := bytes.Runes([]byte(offerName))
-	if len(nameRunes) > 50 {
-		offerName = trimOfferNameByWords(offerName)
+	keys := titleKeys{brand: pos.Brand, number: pos.Number}
+	if pos.CustomNumber != "" {
+		keys.number = pos.CustomNumber
 	}
-
-	return offerName
-}
-
-// getOfferNameByCategory формирует название позиции товара в зависимости от его категории.
-func getOfferNameByCategory(pos position, props map[string]any) string {
-
-	var offerName string
-	switch pos.Category {
-	case "tires":
-		offerName = "Шины " + pos.Brand + " " +
-			getString(props["catalog_model"]) + " " +
-			getString(props["width"]) + "/" +
-			getString(props["height"]) + "R" +
-			getString(props["diameter"]) + " " +
-			getString(props["load_index"]) + " " +
-			getString(props["speed_index"])
-	case "disks":
-		offerName = "Диск " + pos.Brand + ", " +
-			getString(props["catalog_model"]) + " " +
-			getString(props["width"]) + "x" +
-			getString(props["diameter"]) + "/" +
-			getString(props["holes"]) + "x" +
-			getString(props["pcd"]) + "ET" +
-			getString(props["et"]) + " " +
-			getString(props["hub_diameter"])
-	case "oils":
-		offerName = pos.Description + " " + pos.Brand
-	default:
-		var number string
-		if pos.CustomNumber != "" {
-			number = pos.CustomNumber
-		} else {
-			number = pos.Number
-		}
-		offerName = pos.Description + " " + number + " " + pos.Brand
+	keys.model, _ = newPropReader(props, "", nil).String("catalog_model")
+	if pos.Condition != 0 {
+		keys.prefix = localization.WearoutPreOwned
 	}
 
-	return offerName
+	return shortener.shorten(offerName, keys)
 }
 
 // deleteNonBreakingSpace удаляет неразрывный пробел из названия,
@@ -99,84 +64,13 @@ func deleteNonBreakingSpace(name string) string {
 	return strings.ReplaceAll(name, "&nbsp;", " ")
 }
 
-// trimOfferNameByWords удаляет слова из наименования позиции.
-func trimOfferNameByWords(offerName string) string {
-
-	nameElems := strings.Split(offerName, " ")
-	for i := 0; i < len(nameElems); {
-
-		nameElems[i] = strings.TrimSpace(nameElems[i])
-		nameElemRunes := bytes.Runes([]byte(nameElems[i]))
-
-		if nameElems[i] == "" {
-			nameElems = append(nameElems[:i], nameElems[i+1:]...)
-			continue
-		} else if len(nameElemRunes) > 50 {
-			nameElems[i] = string(nameElemRunes[:50])
-		}
-
-		i++
-	}
-
-	if len(nameElems) == 1 && len(bytes.Runes([]byte(nameElems[0]))) <= 50 {
-		return nameElems[0]
-	} else if len(nameElems) == 0 {
-		return ""
-	}
-
-	nameElems = deleteWords(nameElems)
-	nameElems = deleteFuncWords(nameElems)
-
-	return strings.Join(nameElems, " ")
-}
-
-// deleteWords удаляет слова из названия позиции,
-// пока его длина не станет меньше или равной 50.
-func deleteWords(nameElems []string) []string {
-
-	name := strings.Join(nameElems, " ")
-	nameRunes := bytes.Runes([]byte(name))
-
-	if len(nameRunes) > 50 {
-		nameElems = nameElems[:len(nameElems)-1]
-		return deleteWords(nameElems)
-	}
-
-	return nameElems
-}
-
-// deleteFuncWords удаляет служебные слова из названия позиции,
-// пока они будут последними.
-func deleteFuncWords(nameElems []string) []string {
-
-	if len(nameElems) == 0 {
-		return nameElems
-	}
-
-	isFuncWord := false
-	funcWords := []string{"и", "в", "к", "c", "с"}
-	for _, word := range funcWords {
-		if nameElems[len(nameElems)-1] == word {
-			isFuncWord = true
-			break
-		}
-	}
-
-	if isFuncWord {
-		nameElems = nameElems[:len(nameElems)-1]
-		return deleteFuncWords(nameElems)
-	}
-
-	return nameElems
-}
-
 // getSet возвращает значение тега <Set> для группы товаров wipers.
-func getSet(propPackCount any) string {
+func getSet(packCount int) string {
 
-	switch propPackCount.(string) {
-	case "1":
+	switch packCount {
+	case 1:
 		return "Нет"
-	case "2":
+	case 2:
 		return "Да"
 	default:
 		return ""
@@ -184,57 +78,21 @@ func getSet(propPackCount any) string {
 }
 
 // getMountingType возвращает значение тега <MountingType> для группы товаров wipers.
-func getMountingType(propConnector any) string {
-
-	mountingTypeMap := map[string]string{
-		"j-hook (крючок)":                        "Hook 9x4",
-		"bayonet (штыковой замок)":               "Bayonet arm",
-		"side pin (боковой штырь) 22мм":          "Side pin 22",
-		"push button (кнопка) 19мм":              "Push button 16",
-		"narrow push putton (узкая кнопка) 16мм": "Narrow push button",
-		"pinch tab (боковой зажим)":              "Pinch tab",
-		"top lock (верхний замок)":               "Top lock",
-		"claw (клешня)":                          "Claw",
-		"pin lock (штырь)":                       "Pin lock",
-		"side pin (боковой штырь) 17мм":          "Side pin 17",
-		"side mounting (боковое крепление)":      "Side mounting",
-		"GWB046 (VATL5.1)":                       "VATL 5.1",
-		"special (специальное)":                  "Hook 9x4",
-		"штырь 4.8/6.5 мм":                       "Pin lock",
-		"GWB044 (DNTL1.1)":                       "DNTL 1.1",
-		"GWB045 (MBTL1.1)":                       "MBTL 1.1",
-		"DYTL1.1":                                "DYTL 1.1",
-		"грузовой крючок 27/6":                   "Hook 9x4",
-		"AeroClip (АэроКлип)":                    "AeroClip",
-		"GWB071":                                 "Hook 9x4",
-		"грузовой крючок 25/6":                   "Hook 9x4",
-		"грузовой крючок 22/6":                   "Hook 9x4",
-		"RBTL2.0 (19мм)":                         "Hook 9x4",
-	}
-
-	switch v := propConnector.(type) {
-	case []any:
-		return mountingTypeMap[v[0].(string)]
-	case string:
-		return mountingTypeMap[v]
-	default:
-		return ""
-	}
+func getMountingType(connector string, mountingTypes *valueDict) string {
+
+	mountingType, _ := mountingTypes.lookup(connector)
+
+	return mountingType
 }
 
-// getMountingType возвращает значение тегов
-// <BrushLength> и <SecondBrushLength> для группы товаров wipers.
+// getBrushLength возвращает значение тегов
+// <BrushLength> и <SecondBrushLength> в миллиметрах для группы товаров wipers.
 // Параметр num обозначает номер параметра length из article api.
 func getBrushLength(propLen any, num string) int {
 
-	lenStr, ok := propLen.(string)
+	length, ok := millimetersTag.roundToInt(propLen)
 	if !ok {
-		return propLen.(int)
-	}
-
-	length, err := strconv.Atoi(lenStr)
-	if err != nil {
-		log.Errorf("Could not convert prop length%s %q for group wipers: %v\n", num, lenStr, err)
+		log.Errorf("Could not convert prop length%s %v for group wipers\n", num, propLen)
 		return 0
 	}
 
@@ -274,72 +132,6 @@ func getTyreType(season string) string {
 	}
 }
 
-func buildOfferDescription(pos position, productDetail string, priorityDescSource int, loc Localization, removeStmtTypeFromDesc bool) string {
-
-	var (
-		d             string
-		statementType string
-		number        string
-	)
-
-	if pos.CustomNumber != "" {
-		number = pos.CustomNumber
-	} else {
-		number = pos.Number
-	}
-	d = "Бренд: " + pos.Brand + ", артикул: " + number + ", "
-
-	if pos.Condition != 0 {
-
-		if removeStmtTypeFromDesc {
-			d = d + loc.WearoutPreOwned + " "
-		} else {
-			switch pos.Condition {
-			case 0:
-				statementType = loc.StatementTypeNew
-			case 10:
-				statementType = loc.StatementTypePerfrect
-			case 30:
-				statementType = loc.StatementTypeGood
-			case 50:
-				statementType = loc.StatementTypeNormal
-			case 70:
-				statementType = loc.StatementTypeBroken
-			case 90:
-				statementType = loc.StatementTypeRepairKit
-			}
-			d = d + loc.WearoutPreOwned + ". " + loc.ListThBuStatement + " - " + statementType + ", "
-		}
-	}
-
-	switch priorityDescSource {
-	case 2:
-		if pos.AdditionalDescription == "" {
-			d = d + pos.Description + ". <br/>"
-		} else {
-			d = d + pos.AdditionalDescription + ". <br/>"
-		}
-	case 3:
-		if pos.AdditionalDescription == "" {
-			d = d + pos.Description
-		} else {
-			d = d + pos.AdditionalDescription
-		}
-		d = d + ". <br/>" + productDetail
-	case 4:
-		if pos.AdditionalDescription != "" {
-			d = d + pos.AdditionalDescription + ". <br/>"
-		}
-		d = d + productDetail
-	case 7:
-		d = d + pos.Description + ". <br/>"
-	default:
-		d = d + pos.Description + ". <br/>" + productDetail
-	}
-
-	return d
-}
-
 // getOfferIDWhCode формирует уникальный идентификатор предложения из внутреннего кода
 // или, при отсутствии кода, из брендa/артикула.
 func getOfferIDWhCode(pos position) string {
@@ -349,27 +141,15 @@ func getOfferIDWhCode(pos position) string {
 		return whCode
 	}
 
-	b := strings.ReplaceAll(pos.Brand, "ё", "е")
-	b = strings.ReplaceAll(b, "&", "_")
-	b = strings.ReplaceAll(b, " ", "")
-
-	return b + "-" + pos.Number
+	return offerIDBrand(pos.Brand) + "-" + pos.Number
 }
 
 func buildOfferID(pos position, avitoOfferID int) string {
 	switch avitoOfferID {
 	case 0:
-		data := []byte(pos.Number + pos.Brand + strconv.Itoa(pos.RouteID))
-
-		s := fmt.Sprintf("%x", md5.Sum(data))
-
-		return s[0:20]
+		return offerIDHash(pos.Number + pos.Brand + strconv.Itoa(pos.RouteID))
 	case 1:
-		data := []byte(pos.Number + pos.Brand)
-
-		s := fmt.Sprintf("%x", md5.Sum(data))
-
-		return s[0:20]
+		return offerIDHash(pos.Number + pos.Brand)
 	case 2:
 		return pos.Brand + "_" + pos.Number
 	case 3:
@@ -379,35 +159,6 @@ func buildOfferID(pos position, avitoOfferID int) string {
 	return ""
 }
 
-// buildDateEnd формирует значение тега <DateEnd>.
-func buildDateEnd(curTime time.Time, endDate int) string {
-
-	switch endDate {
-	case 1:
-		return curTime.Add(time.Hour * 24).Format(time.DateOnly)
-	case 2:
-		return curTime.Add(time.Hour * 24 * 2).Format(time.DateOnly)
-	case 3:
-		return curTime.Add(time.Hour * 24 * 3).Format(time.DateOnly)
-	case 4:
-		return curTime.Add(time.Hour * 24 * 4).Format(time.DateOnly)
-	case 5:
-		return curTime.Add(time.Hour * 24 * 5).Format(time.DateOnly)
-	case 6:
-		return curTime.Add(time.Hour * 24 * 6).Format(time.DateOnly)
-	case 7:
-		return curTime.Add(time.Hour * 24 * 7).Format(time.DateOnly)
-	case 8:
-		return curTime.Add(time.Hour * 24 * 10).Format(time.DateOnly)
-	case 9:
-		return curTime.Add(time.Hour * 24 * 14).Format(time.DateOnly)
-	case 10:
-		return curTime.Add(time.Hour * 24 * -1).Format(time.DateOnly)
-	default:
-		return ""
-	}
-}
-
 // addWipersParams добавляет значения параметров
 // "Место установки" и "Производитель" в []xmlParam
 // только для группы товаров wipers (щётки стеклоочистителя),
@@ -473,53 +224,11 @@ func buildProductDetail(xmlParams []xmlParam) string {
 
 // getApplicabilityProp получает значение свойства applicability
 // для категории товаров gear_oils.
-func getApplicabilityProp(props map[string]any) string {
-
-	if appProp, ok := props["applicability"]; ok {
-		switch prop := appProp.(type) {
-		case []any:
-			return prop[0].(string)
-		default:
-			return ""
-		}
-	}
+func getApplicabilityProp(p propReader) string {
 
-	return ""
-}
+	applicability, _ := p.String("applicability")
 
-// buildVehicleType устанавливает значение типа транспортного средства для выходного xml-файла.
-func buildVehicleType(props map[string]any) string {
-
-	var vehicleType string
-	if props["age"] == "для детей" || props["age"] == "для подростков" {
-		vehicleType = "Детские"
-	} else {
-		if props["type"] == "горный" {
-			vehicleType = "Горные"
-		}
-		if props["type"] == "bmx" {
-			vehicleType = "BMX"
-		}
-	}
-
-	if vehicleType == "" {
-		vehicleType = "Дорожные"
-	}
-
-	return vehicleType
-}
-
-// getAbcpATFByType получает значение abcp параметра atf_spec по типу данных.
-func getAbcpATFByType(prop any) string {
-
-	switch val := prop.(type) {
-	case string:
-		return val
-	case []any:
-		return val[0].(string)
-	default:
-		return ""
-	}
+	return applicability
 }
 
 // getATF формирует значение тега <ATF>.
@@ -538,43 +247,6 @@ func newCharData(s string) charData {
 	return charData{[]byte("<![CDATA[" + s + "]]>")}
 }
 
-// addImageIncParam добавляет в url-адрес картинки параметр "?<ImageInc>", если он больше нуля.
-func addImageIncParam(imgURL string, imageInc int) string {
-
-	if imageInc > 0 {
-		imgURL += "?" + strconv.Itoa(imageInc)
-	}
-
-	return imgURL
-}
-
-// buildImgURLOffer возвращает ссылку на изображение для:
-// стандартного отображения - https://pubimg.4mycar.ru/images/
-// альтернативного отображения - https://img.autorus.ru/
-func buildImgURLOffer(imgName, pfx, alternativeImageProxy, brand, number string, disableAlternativeProxy bool, updatePhoto bool, updatePhotoCount int) string {
-
-	if alternativeImageProxy != "" && !disableAlternativeProxy {
-		brand = replaceModAutorus(brand, false)
-		number = replaceModAutorus(number, true)
-
-		alternativeImageProxy = strings.TrimSuffix(alternativeImageProxy, "/")
-		alternativeImageURL := alternativeImageProxy + "/images/" + strings.Replace(url.PathEscape(brand), "+", "%20", -1) + "/" + url.PathEscape(number) + "/full/" + imgName
-		if updatePhoto || updatePhotoCount != 0 {
-			updatePhotoCountStr := strconv.Itoa(updatePhotoCount)
-			alternativeImageURL += "?" + updatePhotoCountStr
-		}
-		return alternativeImageURL
-	}
-
-	if strings.Contains(imgName, "http://") || strings.Contains(imgName, "https://") {
-		return imgName
-	}
-
-	enc := encode4chars(imgName)
-	imgName = addFileNamePostfix(enc, pfx)
-	return "https://pubimg.nodacdn.net/images/" + imgName
-}
-
 // contactMethodConst содержит коды и текстовые значения способов обратной связи.
 var contactMethodConst map[int]string = map[int]string{
 	0: "По телефону и в сообщениях",
@@ -705,65 +377,27 @@ func getRimType(diskType string) string {
 }
 
 // getSAEGearOils формирует значение тега <SAE> для группы товаров gear_oils.
-func getSAEGearOils(prop any) string {
+func getSAEGearOils(p propReader) string {
 
-	switch val := prop.(type) {
-	case string:
-		if len(strings.TrimSpace(val)) != 0 {
-			return val
-		}
-
-		return "Не подлежит классификации по SAE"
-	case []string:
-		return strings.Join(val, ",")
-	default:
-		return ""
+	if sae := getSAE(p); strings.TrimSpace(sae) != "" {
+		return sae
 	}
-}
 
-// getSAE формирует значение тега <SAE>.
-func getSAE(prop any) string {
-
-	switch val := prop.(type) {
-	case string:
-		return val
-	case []string:
-		return strings.Join(val, ",")
-	default:
-		return ""
-	}
+	return "Не подлежит классификации по SAE"
 }
 
-// getACEA формирует значение тега <ACEA>.
-func getACEA(prop any) string {
-
-	switch val := prop.(type) {
-	case string:
-		return val
-	case []any:
-		return val[0].(string)
-	default:
-		return ""
-	}
-}
+// getSAE формирует значение тега <SAE>.
+func getSAE(p propReader) string {
 
-// getDOT формирует значение тега <DOT>.
-func getDOT(prop any) string {
+	viscosity, _ := p.Strings("viscosity")
 
-	switch val := prop.(type) {
-	case string:
-		return val
-	case []any:
-		return val[0].(string)
-	default:
-		return ""
-	}
+	return strings.Join(viscosity, ",")
 }
 
 // getPolarity формирует значение тега <Polarity>.
-func getPolarity(prop any) string {
+func getPolarity(polarity string) string {
 
-	switch prop.(string) {
+	switch polarity {
 	case "inverse", "обратная":
 		return "Обратная"
 	case "direct", "прямая":
@@ -792,45 +426,29 @@ func getQuantity(tiresQuantityType, tiresQuantity int, posPacking string) int {
 }
 
 // getAPISpec формирует значение тега <API>.
-func getAPISpec(prop any) any {
-
-	var res any
-	switch val := prop.(type) {
-	case []any:
-		var options []string
-		for _, specOpt := range val {
-			options = append(options, specOpt.(string))
-		}
+func getAPISpec(options []string) any {
 
-		if len(options) > 1 {
-			res = apiTagStructSlise{
-				Option: options,
-			}
-		} else if len(options) == 1 {
-			res = options[0]
-		} else {
-			res = nil
+	switch len(options) {
+	case 0:
+		return nil
+	case 1:
+		return options[0]
+	default:
+		return apiTagStructSlise{
+			Option: options,
 		}
-	case string:
-		res = val
 	}
-
-	return res
 }
 
 // getOEMOil формирует значение тега <OEMOil>.
-func getOEMOil(prop any, avitoOemSpec map[string]string) []string {
+func getOEMOil(abcpOptions []string, avitoOemSpec map[string]string) []string {
 
 	var options []string
-	switch oemOpts := prop.(type) {
-	case []any:
-		for _, oemOpt := range oemOpts {
-			abcpOEM := oemOpt.(string)
-			if avitoOEM, ok := avitoOemSpec[abcpOEM]; ok {
-				options = append(options, avitoOEM)
-			} else {
-				options = append(options, abcpOEM)
-			}
+	for _, abcpOEM := range abcpOptions {
+		if avitoOEM, ok := avitoOemSpec[abcpOEM]; ok {
+			options = append(options, avitoOEM)
+		} else {
+			options = append(options, abcpOEM)
 		}
 	}
 
@@ -858,18 +476,18 @@ func (t currentTime) getTireYear(avitoTireYear string) string {
 }
 
 // setOilsTags устанавливает значения в тегах для группы товаров oils.
-func setOilsTags(offer xmlOffer, props map[string]any, number string) xmlOffer {
+func setOilsTags(offer xmlOffer, p propReader, number string) xmlOffer {
 
-	if _, ok := props["viscosity"]; ok {
-		offer.SAE = getSAE(props["viscosity"])
+	if p.Has("viscosity") {
+		offer.SAE = getSAE(p)
 	}
 
-	if _, ok := props["liquid_volume"]; ok {
-		offer.Volume = replaceSeparatorToComma(props["liquid_volume"].(string)) + " л"
+	if volume, ok := p.Raw("liquid_volume"); ok {
+		offer.Volume = volumeTag.format(volume)
 	}
 
-	if _, ok := props["acea_spec"]; ok {
-		offer.ACEA = getACEA(props["acea_spec"])
+	if acea, ok := p.String("acea_spec"); ok {
+		offer.ACEA = acea
 	}
 
 	offer.VendorCode = number
@@ -877,16 +495,12 @@ func setOilsTags(offer xmlOffer, props map[string]any, number string) xmlOffer {
 }
 
 // setGearOilsTags устанавливает значения в тегах для группы товаров gear_oils.
-func setGearOilsTags(offer xmlOffer, props map[string]any, number string) xmlOffer {
+func setGearOilsTags(offer xmlOffer, p propReader, number string) xmlOffer {
 
-	if _, ok := props["viscosity"]; ok {
-		offer.SAE = getSAEGearOils(props["viscosity"])
-	} else {
-		offer.SAE = "Не подлежит классификации по SAE"
-	}
+	offer.SAE = getSAEGearOils(p)
 
-	if _, ok := props["liquid_volume"]; ok {
-		offer.Volume = replaceSeparatorToComma(props["liquid_volume"].(string)) + " л"
+	if volume, ok := p.Raw("liquid_volume"); ok {
+		offer.Volume = volumeTag.format(volume)
 	}
 
 	offer.VendorCode = number
@@ -894,14 +508,14 @@ func setGearOilsTags(offer xmlOffer, props map[string]any, number string) xmlOff
 }
 
 // setBrakeFluidsTags устанавливает значения в тегах для группы товаров brake_fluids.
-func setBrakeFluidsTags(offer xmlOffer, props map[string]any, number string) xmlOffer {
+func setBrakeFluidsTags(offer xmlOffer, p propReader, number string) xmlOffer {
 
-	if _, ok := props["dot_spec"]; ok {
-		offer.DOT = getDOT(props["dot_spec"])
+	if dot, ok := p.String("dot_spec"); ok {
+		offer.DOT = dot
 	}
 
-	if _, ok := props["liquid_volume"]; ok {
-		offer.Volume = replaceSeparatorToComma(props["liquid_volume"].(string)) + " л"
+	if volume, ok := p.Raw("liquid_volume"); ok {
+		offer.Volume = volumeTag.format(volume)
 	}
 
 	offer.VendorCode = number
@@ -909,14 +523,14 @@ func setBrakeFluidsTags(offer xmlOffer, props map[string]any, number string) xml
 }
 
 // setCoolantTags устанавливает значения в тегах для группы товаров coolant.
-func setCoolantTags(offer xmlOffer, props map[string]any, number string) xmlOffer {
+func setCoolantTags(offer xmlOffer, p propReader, number string) xmlOffer {
 
-	if _, ok := props["coolant_color"]; ok {
-		offer.Color = props["coolant_color"].(string)
+	if color, ok := p.String("coolant_color"); ok {
+		offer.Color = color
 	}
 
-	if _, ok := props["liquid_volume"]; ok {
-		offer.Volume = replaceSeparatorToComma(props["liquid_volume"].(string)) + " л"
+	if volume, ok := p.Raw("liquid_volume"); ok {
+		offer.Volume = volumeTag.format(volume)
 	}
 
 	offer.VendorCode = number
@@ -924,64 +538,63 @@ func setCoolantTags(offer xmlOffer, props map[string]any, number string) xmlOffe
 }
 
 // setBatteriesTags устанавливает значения в тегах для группы товаров batteries.
-func setBatteriesTags(offer xmlOffer, props map[string]any) xmlOffer {
+func setBatteriesTags(offer xmlOffer, p propReader) xmlOffer {
 
-	if _, ok := props["voltage"]; ok {
-		offer.Voltage = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(props["voltage"].(string),
-			"V", ""), "В", ""), "V/В", "")
+	if voltage, ok := p.Raw("voltage"); ok {
+		offer.Voltage = voltageTag.format(voltage)
 	}
 
-	if _, ok := props["capacity"]; ok {
-		offer.Capacity = props["capacity"].(string)
+	if capacity, ok := p.Raw("capacity"); ok {
+		offer.Capacity = capacityTag.format(capacity)
 	}
 
-	if _, ok := props["cca"]; ok {
-		offer.DCL = props["cca"].(string)
+	if cca, ok := p.Raw("cca"); ok {
+		offer.DCL = currentTag.format(cca)
 	}
 
-	if _, ok := props["polarity"]; ok {
-		offer.Polarity = getPolarity(props["polarity"])
+	if polarity, ok := p.String("polarity"); ok {
+		offer.Polarity = getPolarity(polarity)
 	}
 
-	if _, ok := props["length"]; ok {
-		offer.TechnicLength = props["length"].(string)
+	if length, ok := p.Raw("length"); ok {
+		offer.TechnicLength = millimetersTag.format(length)
 	}
 
-	if _, ok := props["width"]; ok {
-		offer.TechnicWidth = props["width"].(string)
+	if width, ok := p.Raw("width"); ok {
+		offer.TechnicWidth = millimetersTag.format(width)
 	}
 
-	if _, ok := props["height"]; ok {
-		offer.TechnicHeight = props["height"].(string)
+	if height, ok := p.Raw("height"); ok {
+		offer.TechnicHeight = millimetersTag.format(height)
 	}
 
 	return offer
 }
 
 // setWipersTags устанавливает значения в тегах для группы товаров wipers.
-func setWipersTags(offer xmlOffer, props map[string]any, brand string) xmlOffer {
+func setWipersTags(offer xmlOffer, p propReader, brand string, mountingTypes *valueDict) xmlOffer {
 
 	offer.InstallationLocation = "Лобовое стекло"
 
-	if _, ok := props["pack_count"]; ok {
-		offer.Set = getSet(props["pack_count"])
+	if packCount, ok := p.Int("pack_count"); ok {
+		offer.Set = getSet(packCount)
 	}
 
-	if _, ok := props["connector"]; ok {
-		offer.MountingType = getMountingType(props["connector"])
+	if connector, ok := p.String("connector"); ok {
+		offer.MountingType = getMountingType(connector, mountingTypes)
 	}
 
-	if _, ok := props["construction"]; ok {
+	if construction, ok := p.String("construction"); ok {
 		caser := cases.Title(language.Russian)
-		offer.BrushType = caser.String(props["construction"].(string))
+		offer.BrushType = caser.String(construction)
 	}
 
-	if _, ok := props["length1"]; ok {
-		offer.BrushLength = getBrushLength(props["length1"], "1")
+	if length, ok := p.Raw("length1"); ok {
+		offer.BrushLength = getBrushLength(length, "1")
 	}
 
-	if _, ok := props["length2"]; ok && offer.Set == "Да" {
-		offer.SecondBrushLength = getBrushLength(props["length2"], "2")
+	if length, ok := p.Raw("length2"); ok && offer.Set == "Да" {
+		offer.SecondBrushLength = getBrushLength(length, "2")
 	}
 
 	offer.BrushBrand = brand
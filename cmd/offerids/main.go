@@ -0,0 +1,84 @@
+// Команда offerids выгружает и загружает хранилище идентификаторов предложений (параметр клиента offerIDStore).
+//
+// Использование:
+//
+//	offerids export -store offer-ids.json [-file ids.csv]
+//	offerids import -store offer-ids.json [-file ids.csv]
+//
+// Формат CSV описан в пакете offerids (Store.Export, Store.Import). Без -file используются
+// стандартные вывод и ввод. Генератор фидов перечитывает изменённый файл в начале задачи,
+// а задача, во время которой выполнена загрузка, сохраняет загруженные соответствия поверх своих
+// (см. offerids.Store.Save).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
+)
+
+func main() {
+
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "offerids:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		return fmt.Errorf("ожидается команда export или import")
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	storePath := fs.String("store", "", "файл хранилища идентификаторов предложений")
+	filePath := fs.String("file", "", "файл CSV, по умолчанию стандартный вывод или ввод")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *storePath == "" {
+		return fmt.Errorf("не задан -store")
+	}
+
+	store, err := offerids.Open(*storePath)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "export" {
+		if *filePath == "" {
+			return store.Export(stdout)
+		}
+		f, err := os.Create(*filePath)
+		if err != nil {
+			return err
+		}
+		if err := store.Export(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	r := stdin
+	if *filePath != "" {
+		f, err := os.Open(*filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	n, err := store.Import(r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "загружено соответствий: %d\n", n)
+
+	return nil
+}
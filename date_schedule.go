@@ -0,0 +1,212 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// legacyDateEndDays содержит смещения в днях для прежних кодов DateEnd.
+var legacyDateEndDays = map[int]int{
+	1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6, 7: 7, 8: 10, 9: 14, 10: -1,
+}
+
+// Условия правил расписания, зависящие от позиции.
+const (
+	dateCondInStock    = "in_stock"
+	dateCondOutOfStock = "out_of_stock"
+)
+
+// dateExpr разбирает выражение даты: основа и/или смещение ("end_of_month", "+30d", "deadline+2d", "2026-12-31").
+var dateExpr = regexp.MustCompile(`^(today|end_of_week|end_of_month|deadline|\d{4}-\d{2}-\d{2})?([+-]?\d+[dwm])?$`)
+
+// dateRule описывает одно правило расписания.
+type dateRule struct {
+	cond   string // пустое значение - правило по умолчанию
+	base   string
+	date   time.Time // для абсолютной даты
+	offset int
+	unit   byte // 'd', 'w' или 'm'
+}
+
+// dateSchedule вычисляет дату тегов <DateEnd> и <DateBegin>.
+//
+// Расписание задаётся выражением или правилами через ";", например
+// "in_stock:end_of_month; out_of_stock:+3d". Выражение содержит основу
+// (today, end_of_week, end_of_month, deadline - сегодня плюс срок поставки позиции,
+// или дата ГГГГ-ММ-ДД) и/или смещение в днях, неделях или месяцах ("+30d", "2w", "-1d").
+// Используется первое правило, условие которого выполнено; правило без условия подходит всегда.
+type dateSchedule struct {
+	rules []dateRule
+}
+
+// parseDateSchedule разбирает расписание text. Пустое расписание возвращает nil.
+func parseDateSchedule(text string) (*dateSchedule, error) {
+
+	s := &dateSchedule{}
+	for _, part := range strings.Split(text, ";") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		var r dateRule
+		if cond, expr, ok := strings.Cut(part, ":"); ok {
+			r.cond, part = strings.TrimSpace(cond), strings.TrimSpace(expr)
+			if r.cond != dateCondInStock && r.cond != dateCondOutOfStock {
+				return nil, errors.Errorf("расписание %q: неизвестное условие %q", text, r.cond)
+			}
+		}
+
+		m := dateExpr.FindStringSubmatch(strings.ReplaceAll(part, " ", ""))
+		if m == nil || m[0] == "" {
+			return nil, errors.Errorf("расписание %q: неверное выражение даты %q", text, part)
+		}
+
+		r.base = m[1]
+		if len(r.base) == len(time.DateOnly) {
+			date, err := time.Parse(time.DateOnly, r.base)
+			if err != nil {
+				return nil, errors.Wrapf(err, "расписание %q", text)
+			}
+			r.date = date
+		}
+		if m[2] != "" {
+			r.unit = m[2][len(m[2])-1]
+			r.offset, _ = strconv.Atoi(strings.TrimPrefix(m[2][:len(m[2])-1], "+"))
+		}
+
+		s.rules = append(s.rules, r)
+	}
+
+	if len(s.rules) == 0 {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// legacyDateSchedule возвращает расписание для прежнего кода DateEnd, nil - тег не заполняется.
+func legacyDateSchedule(code int) *dateSchedule {
+
+	days, ok := legacyDateEndDays[code]
+	if !ok {
+		return nil
+	}
+
+	return &dateSchedule{rules: []dateRule{{offset: days, unit: 'd'}}}
+}
+
+// date возвращает дату расписания для позиции pos в момент now.
+// Дата вычисляется в часовом поясе now. Возвращает false, если ни одно правило не подходит.
+func (s *dateSchedule) date(now time.Time, pos position) (time.Time, bool) {
+
+	if s == nil {
+		return time.Time{}, false
+	}
+
+	for _, r := range s.rules {
+		switch r.cond {
+		case dateCondInStock:
+			if pos.Availability <= 0 {
+				continue
+			}
+		case dateCondOutOfStock:
+			if pos.Availability > 0 {
+				continue
+			}
+		}
+
+		return r.apply(now, pos), true
+	}
+
+	return time.Time{}, false
+}
+
+func (r dateRule) apply(now time.Time, pos position) time.Time {
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	date := today
+	switch r.base {
+	case "", "today":
+	case "end_of_week":
+		date = today.AddDate(0, 0, (7-int(today.Weekday()))%7)
+	case "end_of_month":
+		date = time.Date(y, m+1, 0, 0, 0, 0, 0, now.Location())
+	case "deadline":
+		date = today.AddDate(0, 0, pos.DeadLine)
+	default:
+		date = time.Date(r.date.Year(), r.date.Month(), r.date.Day(), 0, 0, 0, 0, now.Location())
+	}
+
+	switch r.unit {
+	case 'd':
+		date = date.AddDate(0, 0, r.offset)
+	case 'w':
+		date = date.AddDate(0, 0, 7*r.offset)
+	case 'm':
+		date = date.AddDate(0, r.offset, 0)
+	}
+
+	return date
+}
+
+// newDateSchedules возвращает расписания тегов <DateEnd> и <DateBegin> и часовой пояс клиента.
+// DateEndRule имеет приоритет над прежним кодом DateEnd.
+func newDateSchedules(avito *avitoParams) (end, begin *dateSchedule, loc *time.Location, err error) {
+
+	loc = time.Local
+	if avito.Timezone != "" {
+		if loc, err = time.LoadLocation(avito.Timezone); err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "часовой пояс %q", avito.Timezone)
+		}
+	}
+
+	if end, err = parseDateSchedule(avito.DateEndRule); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "DateEndRule")
+	}
+	if end == nil {
+		end = legacyDateSchedule(avito.DateEnd)
+	}
+
+	if begin, err = parseDateSchedule(avito.DateBegin); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "DateBegin")
+	}
+
+	return end, begin, loc, nil
+}
+
+// clock возвращает текущее время в часовом поясе клиента.
+func (b *offerBuilder) clock() time.Time {
+
+	now := time.Now
+	if b.now != nil {
+		now = b.now
+	}
+
+	if b.location == nil {
+		return now()
+	}
+
+	return now().In(b.location)
+}
+
+// buildDates заполняет теги <DateEnd> и <DateBegin> предложения позиции pos.
+// Дата начала в прошлом или сегодня не выводится: объявление публикуется сразу.
+func (b *offerBuilder) buildDates(offer *xmlOffer, pos position) {
+
+	now := b.clock()
+
+	if end, ok := b.dateEnd.date(now, pos); ok {
+		offer.DateEnd = end.Format(time.DateOnly)
+	}
+
+	if begin, ok := b.dateBegin.date(now, pos); ok && begin.After(now) {
+		offer.DateBegin = begin.Format(time.DateOnly)
+	}
+}
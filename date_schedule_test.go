@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateSchedule(t *testing.T) {
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	// 22:30 UTC среды 28.10.2026 - уже четверг 29.10.2026 по Москве.
+	now := time.Date(2026, 10, 28, 22, 30, 0, 0, time.UTC).In(moscow)
+
+	inStock := position{Availability: 5, DeadLine: 3}
+	outOfStock := position{DeadLine: 3}
+
+	tests := []struct {
+		name     string
+		schedule string
+		pos      position
+		want     string
+		wantErr  bool
+	}{
+		{name: "Days", schedule: "30d", want: "2026-11-28"},
+		{name: "Weeks", schedule: "+2w", want: "2026-11-12"},
+		{name: "Months", schedule: "+1m", want: "2026-11-29"},
+		{name: "Negative", schedule: "-1d", want: "2026-10-28"},
+		{name: "End of week", schedule: "end_of_week", want: "2026-11-01"},
+		{name: "End of month", schedule: "end_of_month", want: "2026-10-31"},
+		{name: "End of month with offset", schedule: "end_of_month+1d", want: "2026-11-01"},
+		{name: "Absolute date", schedule: "2026-12-31", want: "2026-12-31"},
+		{name: "Lead time", schedule: "deadline+2d", pos: inStock, want: "2026-11-03"},
+		{name: "In stock rule", schedule: "in_stock:end_of_month; out_of_stock:+3d", pos: inStock, want: "2026-10-31"},
+		{name: "Out of stock rule", schedule: "in_stock:end_of_month; out_of_stock:+3d", pos: outOfStock, want: "2026-11-01"},
+		{name: "No matching rule", schedule: "in_stock:+3d", pos: outOfStock},
+		{name: "Empty", schedule: " ; "},
+		{name: "Unknown condition", schedule: "sold:+1d", wantErr: true},
+		{name: "Unknown expression", schedule: "tomorrow", wantErr: true},
+		{name: "Invalid date", schedule: "2026-02-30", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseDateSchedule(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := ""
+			if date, ok := s.date(now, tt.pos); ok {
+				got = date.Format(time.DateOnly)
+			}
+			if got != tt.want {
+				t.Errorf("date() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDates(t *testing.T) {
+
+	now := func() time.Time { return time.Date(2026, 10, 28, 22, 30, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		avito     avitoParams
+		wantEnd   string
+		wantBegin string
+		wantErr   bool
+	}{
+		{name: "Legacy code", avito: avitoParams{DateEnd: 9}, wantEnd: "2026-11-11"},
+		{name: "Legacy code in client timezone", avito: avitoParams{DateEnd: 1, Timezone: "Europe/Moscow"}, wantEnd: "2026-10-30"},
+		{name: "Legacy code out of range", avito: avitoParams{DateEnd: 11}},
+		{name: "Rule over legacy code", avito: avitoParams{DateEnd: 1, DateEndRule: "end_of_month"}, wantEnd: "2026-10-31"},
+		{name: "Scheduled launch", avito: avitoParams{DateBegin: "2026-11-02"}, wantBegin: "2026-11-02"},
+		{name: "Launch today is immediate", avito: avitoParams{DateBegin: "today"}},
+		{name: "Unknown timezone", avito: avitoParams{Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "Invalid begin", avito: avitoParams{DateBegin: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &offerBuilder{avito: &tt.avito, now: now}
+
+			var err error
+			b.dateEnd, b.dateBegin, b.location, err = newDateSchedules(&tt.avito)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newDateSchedules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// Без Timezone используется часовой пояс сервера, в тесте - UTC.
+			if tt.avito.Timezone == "" {
+				b.location = time.UTC
+			}
+
+			var offer xmlOffer
+			b.buildDates(&offer, position{})
+			if offer.DateEnd != tt.wantEnd || offer.DateBegin != tt.wantBegin {
+				t.Errorf("buildDates() = %q, %q, want %q, %q", offer.DateEnd, offer.DateBegin, tt.wantEnd, tt.wantBegin)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// descriptionPropsTable содержит имя справочника хранилища с настройками свойств,
+// которые выводятся в списке параметров описания.
+//
+// Для группы товаров справочник содержит правила свойств: имя свойства -> "required" или "excluded".
+// Для ключа globalGoodsGroup справочник содержит общие настройки:
+// "whitelist_sources" - значения PriorityDescriptionSource через запятую,
+// при которых в описание выводятся только обязательные свойства.
+const descriptionPropsTable = "DescriptionProps"
+
+const (
+	propRuleRequired = "required"
+	propRuleExcluded = "excluded"
+
+	whitelistSourcesSetting = "whitelist_sources"
+)
+
+// defaultRequiredProps содержит встроенный список обязательных свойств по группам товаров.
+// Используется, если справочник хранилища не содержит обязательных свойств группы.
+var defaultRequiredProps = map[string][]string{
+	"bicycles":        {"age", "type", "wheel_diameter", "frame_size", "speeds"},
+	"gear_oils":       {"atf_spec", "viscosity", "liquid_volume", "api_spec"},
+	"compressor_oils": {"liquid_volume"},
+	"oils":            {"viscosity", "liquid_volume", "acea_spec", "api_spec"},
+	"brake_fluids":    {"dot_spec", "liquid_volume"},
+	"coolant":         {"coolant_color", "liquid_volume"},
+	"batteries":       {"voltage", "capacity", "cca", "polarity", "length", "width", "height"},
+	"wheel_covers":    {"diameter"},
+	"moto_tires":      {"axle", "diameter", "holes", "pcd", "et", "width", "height"},
+	"truck_tires":     {"axle", "season", "diameter", "holes", "pcd", "et", "width", "height"},
+	"tires":           {"axle", "season", "diameter", "holes", "pcd", "et", "width", "height"},
+	"disks":           {"axle", "diameter", "disk_type", "holes", "pcd", "et", "width", "hub_diameter"},
+	"wipers":          {"pack_count", "connector", "construction", "length1", "length2"},
+}
+
+// defaultWhitelistSources содержит значения PriorityDescriptionSource,
+// при которых в описание выводятся только обязательные свойства.
+var defaultWhitelistSources = map[int]bool{0: true, 1: true, 3: true, 4: true}
+
+// propsFilter определяет, какие свойства группы товаров выводятся в списке параметров описания.
+// Если restricted, выводятся только свойства whitelist; исключённые свойства не выводятся никогда.
+type propsFilter struct {
+	restricted bool
+	whitelist  map[string]bool
+	blacklist  map[string]bool
+}
+
+// allowed сообщает, выводится ли свойство prop в списке параметров описания.
+func (f propsFilter) allowed(prop string) bool {
+
+	if f.blacklist[prop] {
+		return false
+	}
+
+	return !f.restricted || f.whitelist[prop]
+}
+
+// descriptionProps содержит настройки свойств описания одной задачи:
+// правила групп товаров из хранилища, встроенные правила и дополнения клиента.
+// Правила группы загружаются из хранилища при первом обращении.
+type descriptionProps struct {
+	spec    func(group string) map[string]string
+	client  map[string][]string
+	sources map[int]bool
+	groups  map[string]propsFilter
+}
+
+// newDescriptionProps создаёт настройки свойств описания по справочнику хранилища.
+// client содержит дополнительные обязательные свойства клиента по группам товаров.
+func newDescriptionProps(client map[string][]string) *descriptionProps {
+	return newDescriptionPropsFrom(func(group string) map[string]string {
+		return PricegenStorage.GetAvitoSpec(descriptionPropsTable, group)
+	}, client)
+}
+
+// newDescriptionPropsFrom создаёт настройки свойств описания по справочнику spec.
+func newDescriptionPropsFrom(spec func(group string) map[string]string, client map[string][]string) *descriptionProps {
+
+	sources := defaultWhitelistSources
+	settings := spec(globalGoodsGroup)
+	if value, ok := settings[whitelistSourcesSetting]; ok {
+		if parsed, err := parseSources(value); err != nil {
+			log.Warnf("Некорректная настройка %s справочника %s: %v", whitelistSourcesSetting, descriptionPropsTable, err)
+		} else {
+			sources = parsed
+		}
+	}
+
+	return &descriptionProps{
+		spec:    spec,
+		client:  client,
+		sources: sources,
+		groups:  make(map[string]propsFilter),
+	}
+}
+
+// filter возвращает фильтр свойств группы товаров group.
+// Список обязательных свойств применяется, только если priorityDescSource
+// входит в настройку whitelist_sources.
+func (d *descriptionProps) filter(group string, priorityDescSource int) propsFilter {
+
+	f, ok := d.groups[group]
+	if !ok {
+		f = d.load(group)
+		d.groups[group] = f
+	}
+
+	if !d.sources[priorityDescSource] {
+		return propsFilter{blacklist: f.blacklist}
+	}
+
+	return f
+}
+
+// load формирует фильтр свойств группы товаров group.
+func (d *descriptionProps) load(group string) propsFilter {
+
+	f := propsFilter{
+		whitelist: make(map[string]bool),
+		blacklist: make(map[string]bool),
+	}
+
+	if group == "" {
+		return f
+	}
+
+	rules := d.spec(group)
+	for _, prop := range sortedKeys(rules) {
+		name := strings.TrimSpace(prop)
+		if name == "" {
+			log.Warnf("Пустое имя свойства в справочнике %s, группа товаров %s", descriptionPropsTable, group)
+			continue
+		}
+
+		switch rule := strings.ToLower(strings.TrimSpace(rules[prop])); rule {
+		case propRuleRequired:
+			f.whitelist[name] = true
+		case propRuleExcluded:
+			f.blacklist[name] = true
+		default:
+			log.Warnf("Неизвестное правило %q свойства %s в справочнике %s, группа товаров %s",
+				rules[prop], name, descriptionPropsTable, group)
+		}
+	}
+
+	if len(f.whitelist) == 0 {
+		for _, prop := range defaultRequiredProps[group] {
+			f.whitelist[prop] = true
+		}
+	}
+
+	// Дополнения клиента расширяют только непустой список обязательных свойств,
+	// иначе они ограничили бы вывод свойств группы.
+	f.restricted = len(f.whitelist) != 0
+	if f.restricted {
+		for _, prop := range d.client[group] {
+			if f.blacklist[prop] {
+				log.Warnf("Свойство %s группы товаров %s исключено из описания и не может быть добавлено клиентом", prop, group)
+				continue
+			}
+			f.whitelist[prop] = true
+		}
+	}
+
+	for prop := range f.whitelist {
+		if f.blacklist[prop] {
+			log.Warnf("Свойство %s группы товаров %s одновременно обязательное и исключённое", prop, group)
+			delete(f.whitelist, prop)
+		}
+	}
+
+	return f
+}
+
+// parseSources разбирает список значений PriorityDescriptionSource через запятую.
+func parseSources(value string) (map[int]bool, error) {
+
+	sources := make(map[int]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		sources[n] = true
+	}
+
+	return sources, nil
+}
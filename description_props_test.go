@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestDescriptionPropsFilter(t *testing.T) {
+
+	spec := map[string]map[string]string{
+		globalGoodsGroup: {whitelistSourcesSetting: "0, 2"},
+		"oils": {
+			"viscosity":     "required",
+			"liquid_volume": " Required ",
+			"descr":         "excluded",
+			"api_spec":      "unknown",
+		},
+		"disks": {
+			"axle": "excluded",
+		},
+	}
+	d := newDescriptionPropsFrom(func(group string) map[string]string { return spec[group] }, map[string][]string{
+		"oils":  {"acea_spec"},
+		"disks": {"axle", "color"},
+	})
+
+	tests := []struct {
+		name   string
+		group  string
+		source int
+		prop   string
+		want   bool
+	}{
+		{name: "Stored required", group: "oils", source: 0, prop: "viscosity", want: true},
+		{name: "Stored rule case and spaces", group: "oils", source: 0, prop: "liquid_volume", want: true},
+		{name: "Unknown rule ignored", group: "oils", source: 0, prop: "api_spec", want: false},
+		{name: "Client addition", group: "oils", source: 0, prop: "acea_spec", want: true},
+		{name: "Stored whitelist replaces default", group: "oils", source: 2, prop: "api_spec", want: false},
+		{name: "Source without whitelist", group: "oils", source: 1, prop: "api_spec", want: true},
+		{name: "Excluded without whitelist", group: "oils", source: 1, prop: "descr", want: false},
+		{name: "Default whitelist", group: "disks", source: 0, prop: "hub_diameter", want: true},
+		{name: "Default without season", group: "disks", source: 0, prop: "season", want: false},
+		{name: "Client addition excluded", group: "disks", source: 0, prop: "axle", want: false},
+		{name: "Client addition to default", group: "disks", source: 0, prop: "color", want: true},
+		{name: "Tires without disk type", group: "tires", source: 0, prop: "disk_type", want: false},
+		{name: "Group without rules", group: "filters", source: 0, prop: "anything", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.filter(tt.group, tt.source).allowed(tt.prop); got != tt.want {
+				t.Errorf("filter(%q, %d).allowed(%q) = %v, want %v", tt.group, tt.source, tt.prop, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSources(t *testing.T) {
+
+	if _, err := parseSources("0,x"); err == nil {
+		t.Errorf("parseSources() error = nil, want error")
+	}
+
+	got, err := parseSources(" 1, ,4")
+	if err != nil || len(got) != 2 || !got[1] || !got[4] {
+		t.Errorf("parseSources() = %v, %v, want map[1:true 4:true]", got, err)
+	}
+}
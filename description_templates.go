@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// descriptionPrefixTemplate формирует начало описания: бренд, артикул и состояние б/у товара.
+const descriptionPrefixTemplate = `Бренд: {{.Brand}}, артикул: {{.Number}}, ` +
+	`{{if .Used}}{{if .RemoveStatementType}}{{.Loc.WearoutPreOwned}} ` +
+	`{{else}}{{.Loc.WearoutPreOwned}}. {{.Loc.ListThBuStatement}} - {{.StatementType}}, {{end}}{{end}}`
+
+// descriptionBodyTemplates содержит встроенные шаблоны основной части описания
+// по значениям PriorityDescriptionSource.
+var descriptionBodyTemplates = map[int]string{
+	2: `{{if .AdditionalDescription}}{{.AdditionalDescription}}{{else}}{{.Description}}{{end}}. <br/>`,
+	3: `{{if .AdditionalDescription}}{{.AdditionalDescription}}{{else}}{{.Description}}{{end}}. <br/>{{.ProductDetail}}`,
+	4: `{{if .AdditionalDescription}}{{.AdditionalDescription}}. <br/>{{end}}{{.ProductDetail}}`,
+	7: `{{.Description}}. <br/>`,
+}
+
+// defaultDescriptionBodyTemplate используется для остальных значений PriorityDescriptionSource.
+const defaultDescriptionBodyTemplate = `{{.Description}}. <br/>{{.ProductDetail}}`
+
+// descriptionDonorTemplate формирует блок с данными автомобиля-донора б/у товара.
+const descriptionDonorTemplate = `{{.DonorDetail}}`
+
+// defaultDescriptionTemplate используется, если клиент не задал свой шаблон описания.
+const defaultDescriptionTemplate = `{{template "prefix" .}}{{template "body" .}}{{template "donor" .}}{{.SalesConditions}}`
+
+// descriptionFuncs содержит функции, доступные в шаблонах описания.
+var descriptionFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// descriptionData содержит данные, доступные в шаблоне описания.
+type descriptionData struct {
+	Brand                 string
+	Number                string
+	Category              string
+	GoodsGroup            string
+	Description           string
+	AdditionalDescription string
+
+	Used                bool
+	Condition           int
+	StatementType       string
+	RemoveStatementType bool
+	// Donor содержит данные автомобиля-донора б/у товара, DonorDetail - их в виде блока описания.
+	Donor       []xmlParam
+	DonorDetail string
+
+	// Props содержит переведённые значения свойств товара, значения массивов разделены "/".
+	Props map[string]string
+	// Params содержит параметры описания в порядке вывода.
+	Params []xmlParam
+	// ProductDetail содержит параметры описания в виде списка <ul>.
+	ProductDetail string
+
+	Loc             Localization
+	SalesConditions string
+
+	PriorityDescriptionSource int
+}
+
+// descriptionTemplate формирует описание предложения по шаблону.
+//
+// Шаблон клиента может использовать встроенные части описания:
+// {{template "prefix" .}}, {{template "body" .}} и {{template "donor" .}}.
+type descriptionTemplate struct {
+	tmpl *template.Template
+}
+
+// newDescriptionTemplate создаёт шаблон описания text для значения PriorityDescriptionSource source.
+// Если text пустой, используется встроенный шаблон.
+// Шаблон проверяется на тестовых данных, чтобы ошибки в именах полей
+// обнаруживались до формирования фида.
+func newDescriptionTemplate(text string, source int) (*descriptionTemplate, error) {
+
+	if strings.TrimSpace(text) == "" {
+		text = defaultDescriptionTemplate
+	}
+
+	body, ok := descriptionBodyTemplates[source]
+	if !ok {
+		body = defaultDescriptionBodyTemplate
+	}
+
+	tmpl, err := template.New("description").
+		Option("missingkey=zero").
+		Funcs(descriptionFuncs).
+		Parse(`{{define "prefix"}}` + descriptionPrefixTemplate + `{{end}}{{define "body"}}` + body + `{{end}}` +
+			`{{define "donor"}}` + descriptionDonorTemplate + `{{end}}` + text)
+	if err != nil {
+		return nil, errors.Wrap(err, "разбор шаблона описания")
+	}
+
+	t := &descriptionTemplate{tmpl: tmpl}
+	if _, err := t.execute(descriptionData{Props: map[string]string{}, Params: []xmlParam{{}}}); err != nil {
+		return nil, errors.Wrap(err, "проверка шаблона описания")
+	}
+
+	return t, nil
+}
+
+// loadDescriptionTemplates возвращает шаблон описания клиента text и встроенный шаблон
+// для значения PriorityDescriptionSource source.
+// Если шаблон клиента содержит ошибку, вместо него используется встроенный.
+func loadDescriptionTemplates(text string, source int) (custom, builtin *descriptionTemplate) {
+
+	builtin, err := newDescriptionTemplate("", source)
+	if err != nil {
+		panic(err)
+	}
+
+	custom, err = newDescriptionTemplate(text, source)
+	if err != nil {
+		log.Errorf("Шаблон описания клиента не используется: %v", err)
+		return builtin, builtin
+	}
+
+	return custom, builtin
+}
+
+// execute формирует описание по данным data.
+func (t *descriptionTemplate) execute(data descriptionData) (string, error) {
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// newDescriptionData формирует данные шаблона описания позиции pos.
+func newDescriptionData(pos position, props Properties, xmlParams []xmlParam, priorityDescSource int,
+	loc Localization, removeStmtTypeFromDesc bool) descriptionData {
+
+	number := pos.Number
+	if pos.CustomNumber != "" {
+		number = pos.CustomNumber
+	}
+
+	return descriptionData{
+		Brand:                     pos.Brand,
+		Number:                    number,
+		Category:                  pos.Category,
+		GoodsGroup:                pos.GoodsGroupCode,
+		Description:               pos.Description,
+		AdditionalDescription:     pos.AdditionalDescription,
+		Used:                      pos.Condition != 0,
+		Condition:                 pos.Condition,
+		StatementType:             getStatementType(pos.Condition, loc),
+		RemoveStatementType:       removeStmtTypeFromDesc,
+		Props:                     templateProps(props),
+		Params:                    xmlParams,
+		ProductDetail:             buildProductDetail(xmlParams),
+		Loc:                       loc,
+		PriorityDescriptionSource: priorityDescSource,
+	}
+}
+
+// templateProps приводит значения свойств товара к строкам для шаблона описания.
+func templateProps(props Properties) map[string]string {
+
+	out := make(map[string]string, len(props))
+	for name, value := range props {
+		values := []any{value}
+		if arr, ok := value.([]any); ok {
+			values = arr
+		}
+
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			if s, ok := scalarString(v); ok {
+				strs = append(strs, s)
+			}
+		}
+
+		if len(strs) != 0 {
+			out[name] = strings.Join(strs, "/")
+		}
+	}
+
+	return out
+}
+
+// getStatementType возвращает описание состояния б/у товара condition.
+func getStatementType(condition int, loc Localization) string {
+
+	switch condition {
+	case 0:
+		return loc.StatementTypeNew
+	case 10:
+		return loc.StatementTypePerfrect
+	case 30:
+		return loc.StatementTypeGood
+	case 50:
+		return loc.StatementTypeNormal
+	case 70:
+		return loc.StatementTypeBroken
+	case 90:
+		return loc.StatementTypeRepairKit
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+package main
+
+import "testing"
+
+func TestBuiltinDescriptionTemplates(t *testing.T) {
+
+	loc := Localization{
+		WearoutPreOwned:   "Б/у",
+		ListThBuStatement: "Состояние",
+		StatementTypeGood: "хорошее",
+	}
+	params := []xmlParam{{Name: "SAE", Content: "5W-30"}}
+
+	tests := []struct {
+		name       string
+		pos        position
+		source     int
+		removeStmt bool
+		want       string
+	}{
+		{
+			name:   "Default source",
+			pos:    position{Brand: "BRAND", Number: "NUM", Description: "Масло"},
+			source: 0,
+			want:   "Бренд: BRAND, артикул: NUM, Масло. <br/><ul><li>SAE: 5W-30.</li></ul> ",
+		},
+		{
+			name:   "Custom number",
+			pos:    position{Brand: "BRAND", Number: "NUM", CustomNumber: "CUSTOM", Description: "Масло"},
+			source: 7,
+			want:   "Бренд: BRAND, артикул: CUSTOM, Масло. <br/>",
+		},
+		{
+			name:   "Additional description without detail",
+			pos:    position{Brand: "BRAND", Number: "NUM", Description: "Масло", AdditionalDescription: "Доп"},
+			source: 2,
+			want:   "Бренд: BRAND, артикул: NUM, Доп. <br/>",
+		},
+		{
+			name:   "Description instead of empty additional",
+			pos:    position{Brand: "BRAND", Number: "NUM", Description: "Масло"},
+			source: 3,
+			want:   "Бренд: BRAND, артикул: NUM, Масло. <br/><ul><li>SAE: 5W-30.</li></ul> ",
+		},
+		{
+			name:   "Only detail without additional",
+			pos:    position{Brand: "BRAND", Number: "NUM", Description: "Масло"},
+			source: 4,
+			want:   "Бренд: BRAND, артикул: NUM, <ul><li>SAE: 5W-30.</li></ul> ",
+		},
+		{
+			name:   "Used with statement type",
+			pos:    position{Brand: "BRAND", Number: "NUM", Description: "Фара", Condition: 30},
+			source: 7,
+			want:   "Бренд: BRAND, артикул: NUM, Б/у. Состояние - хорошее, Фара. <br/>",
+		},
+		{
+			name:       "Used without statement type",
+			pos:        position{Brand: "BRAND", Number: "NUM", Description: "Фара", Condition: 30},
+			source:     7,
+			removeStmt: true,
+			want:       "Бренд: BRAND, артикул: NUM, Б/у Фара. <br/>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newDescriptionTemplate("", tt.source)
+			if err != nil {
+				t.Fatalf("newDescriptionTemplate() error = %v", err)
+			}
+
+			got, err := tmpl.execute(newDescriptionData(tt.pos, nil, params, tt.source, loc, tt.removeStmt))
+			if err != nil || got != tt.want {
+				t.Errorf("execute() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomDescriptionTemplate(t *testing.T) {
+
+	text := `{{template "prefix" .}}{{upper .Props.viscosity}}, {{.Props.api_spec}}{{range .Params}}; {{.Name}}={{.Content}}{{end}}` +
+		`{{.Props.unknown}}. {{template "body" .}}{{.SalesConditions}}`
+
+	tmpl, err := newDescriptionTemplate(text, 7)
+	if err != nil {
+		t.Fatalf("newDescriptionTemplate() error = %v", err)
+	}
+
+	props := Properties{"viscosity": "5w-30", "api_spec": []any{"SN", "CF"}}
+	data := newDescriptionData(position{Brand: "BRAND", Number: "NUM", Description: "Масло"}, props,
+		[]xmlParam{{Name: "SAE", Content: "5W-30"}}, 7, Localization{}, false)
+	data.SalesConditions = " Доставка."
+
+	want := "Бренд: BRAND, артикул: NUM, 5W-30, SN/CF; SAE=5W-30. Масло. <br/> Доставка."
+	if got, err := tmpl.execute(data); err != nil || got != want {
+		t.Errorf("execute() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestInvalidDescriptionTemplate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "Syntax error", text: "{{.Brand"},
+		{name: "Unknown field", text: "{{.Name}}"},
+		{name: "Unknown function", text: "{{title .Brand}}"},
+		{name: "Unknown template", text: `{{template "footer" .}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newDescriptionTemplate(tt.text, 0); err == nil {
+				t.Errorf("newDescriptionTemplate(%q) error = nil, want error", tt.text)
+			}
+		})
+	}
+
+	custom, builtin := loadDescriptionTemplates("{{.Name}}", 0)
+	if custom != builtin {
+		t.Errorf("loadDescriptionTemplates() returned invalid custom template")
+	}
+}
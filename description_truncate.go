@@ -0,0 +1,178 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// descriptionEllipsis добавляется в конец сокращённого текста.
+const descriptionEllipsis = "..."
+
+// Разделы описания, которые сокращаются, если описание длиннее descCharacterLimit.
+const (
+	sectionParams                = "params"
+	sectionDescription           = "description"
+	sectionAdditionalDescription = "additional_description"
+)
+
+// descriptionShrinkOrder задаёт порядок сокращения разделов описания.
+// Заголовок (бренд, артикул, состояние) и условия продажи не сокращаются.
+var descriptionShrinkOrder = []string{sectionParams, sectionDescription, sectionAdditionalDescription}
+
+// descriptionExcess возвращает, на сколько символов описание d длиннее descCharacterLimit.
+func descriptionExcess(d string) int {
+	return utf8.RuneCountInString(d) - descCharacterLimit
+}
+
+// shrinkDescriptionSection сокращает раздел section данных описания на excess символов:
+// удаляет последний параметр или сокращает текст по границе слова.
+// Возвращает false, если раздел сокращать больше некуда.
+func shrinkDescriptionSection(data *descriptionData, section string, excess int) bool {
+
+	switch section {
+	case sectionParams:
+		if len(data.Params) == 0 {
+			return false
+		}
+		data.Params = data.Params[:len(data.Params)-1]
+		data.ProductDetail = buildProductDetail(data.Params)
+	case sectionDescription:
+		return shrinkText(&data.Description, excess)
+	case sectionAdditionalDescription:
+		return shrinkText(&data.AdditionalDescription, excess)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// shrinkText сокращает текст s не менее чем на excess символов по границе слова.
+func shrinkText(s *string, excess int) bool {
+
+	text := strings.TrimSuffix(*s, descriptionEllipsis)
+	if text == "" {
+		return false
+	}
+
+	limit := utf8.RuneCountInString(text) - excess - utf8.RuneCountInString(descriptionEllipsis)
+	if limit <= 0 {
+		*s = ""
+		return true
+	}
+
+	cut := cutAtWord(text, limit)
+	if cut == "" {
+		*s = ""
+		return true
+	}
+	*s = cut + descriptionEllipsis
+
+	return true
+}
+
+// cutAtWord возвращает начало текста s не длиннее limit символов, обрезанное по границе слова.
+// Мнемоники вида &amp; не разрываются.
+func cutAtWord(s string, limit int) string {
+
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	cut := string(runes[:limit])
+	if runes[limit] != ' ' {
+		if i := strings.LastIndexAny(cut, " \t"); i >= 0 {
+			cut = cut[:i]
+		} else {
+			cut = ""
+		}
+	}
+
+	if i := strings.LastIndexByte(cut, '&'); i >= 0 && !strings.Contains(cut[i:], ";") {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " \t,;:")
+}
+
+// truncateDescriptionHTML сокращает описание d до limit символов, не разрывая теги:
+// описание обрезается перед тегом или по границе слова, а открытые теги закрываются.
+// Описание должно быть обработано sanitizeDescription.
+func truncateDescriptionHTML(d string, limit int) string {
+
+	var (
+		b       strings.Builder
+		open    []string
+		used    int
+		closing int
+	)
+
+	ellipsis := utf8.RuneCountInString(descriptionEllipsis)
+
+	finish := func() string {
+		b.WriteString(descriptionEllipsis)
+		for i := len(open) - 1; i >= 0; i-- {
+			b.WriteString("</" + open[i] + ">")
+		}
+		return b.String()
+	}
+
+	for d != "" {
+		if d[0] == '<' {
+			end := strings.IndexByte(d, '>')
+			if end < 0 {
+				break
+			}
+
+			tag := d[:end+1]
+			d = d[end+1:]
+			size := utf8.RuneCountInString(tag)
+
+			switch {
+			case strings.HasPrefix(tag, "</"):
+				b.WriteString(tag)
+				used += size
+				if len(open) != 0 {
+					open = open[:len(open)-1]
+					closing -= size
+				}
+			case strings.HasSuffix(tag, "/>"):
+				if used+size+closing+ellipsis > limit {
+					return finish()
+				}
+				b.WriteString(tag)
+				used += size
+			default:
+				name := strings.Trim(tag, "<>")
+				closeSize := len(name) + 3
+				if used+size+closing+closeSize+ellipsis > limit {
+					return finish()
+				}
+				b.WriteString(tag)
+				used += size
+				open = append(open, name)
+				closing += closeSize
+			}
+
+			continue
+		}
+
+		end := strings.IndexByte(d, '<')
+		if end < 0 {
+			end = len(d)
+		}
+
+		text := d[:end]
+		d = d[end:]
+		size := utf8.RuneCountInString(text)
+		if used+size+closing+ellipsis > limit {
+			b.WriteString(cutAtWord(text, limit-used-closing-ellipsis))
+			return finish()
+		}
+		b.WriteString(text)
+		used += size
+	}
+
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateDescriptionHTML(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		in    string
+		limit int
+		want  string
+	}{
+		{
+			name:  "Fits",
+			in:    "<p>Текст</p>",
+			limit: 20,
+			want:  "<p>Текст</p>",
+		},
+		{
+			name:  "Cut by word with closed tags",
+			in:    "<ul><li>Первый пункт списка</li></ul>",
+			limit: 30,
+			want:  "<ul><li>Первый...</li></ul>",
+		},
+		{
+			name:  "Whole block dropped before tag",
+			in:    "Заголовок<ul><li>Пункт</li></ul>",
+			limit: 20,
+			want:  "Заголовок...",
+		},
+		{
+			name:  "Not cut inside br",
+			in:    "Строка один<br/>Строка два",
+			limit: 15,
+			want:  "Строка один...",
+		},
+		{
+			name:  "Entity not split",
+			in:    "Tom&amp;Jerry",
+			limit: 10,
+			want:  "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateDescriptionHTML(tt.in, tt.limit)
+			if got != tt.want {
+				t.Errorf("truncateDescriptionHTML() = %q, want %q", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("truncateDescriptionHTML() length = %d, want <= %d", n, tt.limit)
+			}
+		})
+	}
+}
+
+func TestBuildFinalDescription(t *testing.T) {
+
+	description, defaultDescription := loadDescriptionTemplates("", 0)
+	b := &offerBuilder{
+		avito:              &avitoParams{SalesConditions: " Доставка и гарантия."},
+		description:        description,
+		defaultDescription: defaultDescription,
+	}
+
+	params := make([]xmlParam, 0, 100)
+	for i := 0; i < 100; i++ {
+		params = append(params, xmlParam{Name: "Параметр", Content: strings.Repeat("з", 50)})
+	}
+
+	tests := []struct {
+		name       string
+		descr      string
+		params     []xmlParam
+		wantParams int
+		wantDesc   bool
+	}{
+		{name: "Params shrunk first", descr: strings.Repeat("слово ", 200), params: params, wantParams: -1, wantDesc: true},
+		{name: "Description shrunk after params", descr: strings.Repeat("слово ", 1300), params: params[:2], wantParams: 0, wantDesc: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := position{Brand: "BRAND", Number: "NUM", Description: tt.descr}
+			got := b.buildFinalDescription(pos, nil, "BRAND|NUM", tt.params)
+
+			if n := utf8.RuneCountInString(got); n > descCharacterLimit {
+				t.Errorf("buildFinalDescription() length = %d, want <= %d", n, descCharacterLimit)
+			}
+			if !strings.HasPrefix(got, "Бренд: BRAND, артикул: NUM, ") {
+				t.Errorf("buildFinalDescription() lost header: %q", got[:50])
+			}
+			if !strings.HasSuffix(got, " Доставка и гарантия.") {
+				t.Errorf("buildFinalDescription() lost sales conditions")
+			}
+			if strings.Count(got, "<ul>") != strings.Count(got, "</ul>") || strings.Count(got, "<li>") != strings.Count(got, "</li>") {
+				t.Errorf("buildFinalDescription() broke markup")
+			}
+
+			params := strings.Count(got, "<li>")
+			if tt.wantParams >= 0 && params != tt.wantParams {
+				t.Errorf("buildFinalDescription() params = %d, want %d", params, tt.wantParams)
+			}
+			if tt.wantParams < 0 && (params == 0 || params == len(tt.params)) {
+				t.Errorf("buildFinalDescription() params = %d, want partially removed", params)
+			}
+			if full := strings.Contains(got, strings.TrimSpace(pos.Description)); full != tt.wantDesc {
+				t.Errorf("buildFinalDescription() full description = %v, want %v", full, tt.wantDesc)
+			}
+		})
+	}
+}
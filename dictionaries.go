@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// defaultMountingTypes содержит встроенное соответствие значений свойства connector
+// из article api значениям тега <MountingType> Авито.
+// Используется, если справочник MountingType в хранилище не содержит значения.
+var defaultMountingTypes = map[string]string{
+	"j-hook (крючок)":                        "Hook 9x4",
+	"bayonet (штыковой замок)":               "Bayonet arm",
+	"side pin (боковой штырь) 22мм":          "Side pin 22",
+	"push button (кнопка) 19мм":              "Push button 16",
+	"narrow push button (узкая кнопка) 16мм": "Narrow push button",
+	"narrow push putton (узкая кнопка) 16мм": "Narrow push button",
+	"pinch tab (боковой зажим)":              "Pinch tab",
+	"top lock (верхний замок)":               "Top lock",
+	"claw (клешня)":                          "Claw",
+	"pin lock (штырь)":                       "Pin lock",
+	"side pin (боковой штырь) 17мм":          "Side pin 17",
+	"side mounting (боковое крепление)":      "Side mounting",
+	"GWB046 (VATL5.1)":                       "VATL 5.1",
+	"special (специальное)":                  "Hook 9x4",
+	"штырь 4.8/6.5 мм":                       "Pin lock",
+	"GWB044 (DNTL1.1)":                       "DNTL 1.1",
+	"GWB045 (MBTL1.1)":                       "MBTL 1.1",
+	"DYTL1.1":                                "DYTL 1.1",
+	"грузовой крючок 27/6":                   "Hook 9x4",
+	"AeroClip (АэроКлип)":                    "AeroClip",
+	"GWB071":                                 "Hook 9x4",
+	"грузовой крючок 25/6":                   "Hook 9x4",
+	"грузовой крючок 22/6":                   "Hook 9x4",
+	"RBTL2.0 (19мм)":                         "Hook 9x4",
+}
+
+// valueDict сопоставляет значения свойства из article api значениям тегов Авито.
+// Значения сравниваются в нормализованном виде (см. normalizeDictKey),
+// несопоставленные значения регистрируются в отчёте задачи.
+type valueDict struct {
+	prop   string
+	values map[string]string
+	report *feedReport
+}
+
+// newValueDict создаёт справочник для свойства prop.
+// Значения stored, загруженные из хранилища, имеют приоритет над встроенными defaults.
+func newValueDict(prop string, defaults, stored map[string]string, report *feedReport) *valueDict {
+
+	d := &valueDict{
+		prop:   prop,
+		values: make(map[string]string, len(defaults)+len(stored)),
+		report: report,
+	}
+
+	for k, v := range defaults {
+		d.values[normalizeDictKey(k)] = v
+	}
+
+	for k, v := range stored {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		d.values[normalizeDictKey(k)] = v
+	}
+
+	return d
+}
+
+// newMountingTypeDict создаёт справочник типов крепления щёток стеклоочистителя.
+func newMountingTypeDict(report *feedReport) *valueDict {
+	return newValueDict("connector", defaultMountingTypes, PricegenStorage.GetAvitoSpec("MountingType", "connector"), report)
+}
+
+// lookup возвращает значение Авито для значения свойства.
+// Пустые значения не считаются несопоставленными.
+func (d *valueDict) lookup(value string) (string, bool) {
+
+	key := normalizeDictKey(value)
+	if key == "" {
+		return "", false
+	}
+
+	if v, ok := d.values[key]; ok {
+		return v, true
+	}
+
+	d.report.addUnmapped(d.prop, strings.TrimSpace(value))
+
+	return "", false
+}
+
+// normalizeDictKey приводит значение к виду для сравнения:
+// нижний регистр, "ё" заменяется на "е", пробельные символы удаляются.
+func normalizeDictKey(s string) string {
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if r == 'ё' {
+			r = 'е'
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValueDictLookup(t *testing.T) {
+
+	report := newFeedReport()
+	dict := newValueDict("connector", defaultMountingTypes, map[string]string{
+		"Hook (Крючок) 12мм": "Hook 12x4",
+		"j-hook (крючок)":    "Hook 9x3",
+		"empty":              "",
+	}, report)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   string
+		wantOk bool
+	}{
+		{name: "Exact default", value: "claw (клешня)", want: "Claw", wantOk: true},
+		{name: "Case and spaces", value: "  Side Pin(боковой  штырь) 22мм", want: "Side pin 22", wantOk: true},
+		{name: "Non-breaking space", value: "pin\u00a0lock (штырь)", want: "Pin lock", wantOk: true},
+		{name: "Typo in source data", value: "narrow push putton (узкая кнопка) 16мм", want: "Narrow push button", wantOk: true},
+		{name: "Stored value", value: "hook (крючок) 12мм", want: "Hook 12x4", wantOk: true},
+		{name: "Stored overrides default", value: "J-Hook (крючок)", want: "Hook 9x3", wantOk: true},
+		{name: "Empty stored value ignored", value: "empty", want: "", wantOk: false},
+		{name: "Unknown", value: "new connector", want: "", wantOk: false},
+		{name: "Blank", value: " ", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := dict.lookup(tt.value)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("lookup(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+
+	want := []string{"empty", "new connector"}
+	if got := report.unmappedValues("connector"); !cmp.Equal(got, want) {
+		t.Errorf("unmappedValues() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// donorVINVisible содержит число последних символов VIN автомобиля-донора, выводимых в описании.
+const donorVINVisible = 6
+
+// Свойства товара с данными автомобиля-донора б/у (контрактной) запчасти.
+// Заполняются клиентом в файле свойств PropertiesURL по бренду и артикулу позиции.
+const (
+	donorPropMake     = "donor_make"
+	donorPropModel    = "donor_model"
+	donorPropYear     = "donor_year"
+	donorPropVIN      = "donor_vin"
+	donorPropMileage  = "donor_mileage"       // км
+	donorPropTested   = "donor_tested"        // запчасть проверена на автомобиле-доноре
+	donorPropWarranty = "donor_warranty_days" // дней
+)
+
+// donorTestedValues содержит строковые значения свойства donor_tested, означающие проверку запчасти.
+var donorTestedValues = map[string]bool{"да": true, "yes": true, "true": true, "1": true}
+
+// donorVehicle содержит данные автомобиля-донора б/у (контрактной) запчасти.
+type donorVehicle struct {
+	Make         string
+	Model        string
+	Year         int
+	VIN          string // выводятся только последние donorVINVisible символов
+	Mileage      int
+	Tested       bool
+	WarrantyDays int
+}
+
+// DonorLocalization содержит подписи блока автомобиля-донора в описании.
+// Встраивается в Localization клиента; незаполненные подписи берутся из defaultDonorLabels.
+type DonorLocalization struct {
+	DonorTitle    string
+	DonorMake     string
+	DonorModel    string
+	DonorYear     string
+	DonorVIN      string
+	DonorMileage  string
+	DonorKm       string
+	DonorTested   string
+	DonorYes      string
+	DonorWarranty string
+	DonorDays     string
+}
+
+// labels возвращает подписи локализации по ключам defaultDonorLabels.
+func (l DonorLocalization) labels() map[string]string {
+	return map[string]string{
+		"title":    l.DonorTitle,
+		"make":     l.DonorMake,
+		"model":    l.DonorModel,
+		"year":     l.DonorYear,
+		"vin":      l.DonorVIN,
+		"mileage":  l.DonorMileage,
+		"km":       l.DonorKm,
+		"tested":   l.DonorTested,
+		"yes":      l.DonorYes,
+		"warranty": l.DonorWarranty,
+		"days":     l.DonorDays,
+	}
+}
+
+// defaultDonorLabels содержит подписи блока автомобиля-донора, если они не заданы в локализации.
+var defaultDonorLabels = map[string]string{
+	"title":    "Автомобиль-донор",
+	"make":     "Марка",
+	"model":    "Модель",
+	"year":     "Год выпуска",
+	"vin":      "VIN",
+	"mileage":  "Пробег",
+	"km":       "км",
+	"tested":   "Проверено на автомобиле",
+	"yes":      "да",
+	"warranty": "Гарантия",
+	"days":     "дн.",
+}
+
+// donorLabels содержит подписи блока автомобиля-донора с учётом локализации клиента.
+type donorLabels map[string]string
+
+// newDonorLabels возвращает подписи локализации клиента localization, дополненные встроенными.
+func newDonorLabels(localization Localization) donorLabels {
+
+	l := make(donorLabels, len(defaultDonorLabels))
+	for key, label := range defaultDonorLabels {
+		l[key] = label
+	}
+	for key, label := range localization.DonorLocalization.labels() {
+		if label = strings.TrimSpace(label); label != "" {
+			l[key] = label
+		}
+	}
+
+	return l
+}
+
+// usedDonor возвращает данные автомобиля-донора из свойств p, если позиция pos б/у
+// и для неё задано хотя бы одно свойство донора.
+func usedDonor(pos position, p propReader) *donorVehicle {
+
+	if pos.Condition == 0 {
+		return nil
+	}
+
+	var d donorVehicle
+	d.Make, _ = p.String(donorPropMake)
+	d.Model, _ = p.String(donorPropModel)
+	d.Year, _ = p.Int(donorPropYear)
+	d.VIN, _ = p.String(donorPropVIN)
+	d.Mileage, _ = p.Int(donorPropMileage)
+	d.WarrantyDays, _ = p.Int(donorPropWarranty)
+
+	if v, ok := p.Raw(donorPropTested); ok {
+		switch tested := v.(type) {
+		case bool:
+			d.Tested = tested
+		default:
+			s, _ := p.String(donorPropTested)
+			d.Tested = donorTestedValues[strings.ToLower(strings.TrimSpace(s))]
+		}
+	}
+
+	if d == (donorVehicle{}) {
+		return nil
+	}
+
+	return &d
+}
+
+// params возвращает заполненные данные автомобиля-донора d в порядке вывода.
+func (l donorLabels) params(d *donorVehicle) []xmlParam {
+
+	if d == nil {
+		return nil
+	}
+
+	var params []xmlParam
+	add := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			params = append(params, xmlParam{Name: l[key], Content: value})
+		}
+	}
+
+	add("make", d.Make)
+	add("model", d.Model)
+	if d.Year > 0 {
+		add("year", strconv.Itoa(d.Year))
+	}
+	add("vin", donorVINFragment(d.VIN))
+	if d.Mileage > 0 {
+		add("mileage", strconv.Itoa(d.Mileage)+" "+l["km"])
+	}
+	if d.Tested {
+		add("tested", l["yes"])
+	}
+	if d.WarrantyDays > 0 {
+		add("warranty", strconv.Itoa(d.WarrantyDays)+" "+l["days"])
+	}
+
+	return params
+}
+
+// detail возвращает блок описания с данными автомобиля-донора params.
+func (l donorLabels) detail(params []xmlParam) string {
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	return "<p>" + l["title"] + ":</p>" + buildProductDetail(params)
+}
+
+// donorVINFragment возвращает последние donorVINVisible символов VIN, скрывая остальные.
+func donorVINFragment(vin string) string {
+
+	vin = strings.ToUpper(strings.Join(strings.Fields(vin), ""))
+	if len(vin) <= donorVINVisible {
+		return vin
+	}
+
+	return strings.Repeat("*", len(vin)-donorVINVisible) + vin[len(vin)-donorVINVisible:]
+}
+
+// setDonorTags заполняет теги предложения offer данными автомобиля-донора d.
+// Не вызывается для шин и дисков: их модель определяется по каталогу.
+func setDonorTags(offer *xmlOffer, d *donorVehicle) {
+
+	if d == nil {
+		return
+	}
+
+	offer.Make = strings.TrimSpace(d.Make)
+	offer.Model = strings.TrimSpace(d.Model)
+	if d.Year > 0 {
+		offer.Year = strconv.Itoa(d.Year)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDonorParams(t *testing.T) {
+
+	donor := Properties{
+		"donor_make":          "Toyota",
+		"donor_model":         "Camry",
+		"donor_year":          2015.0,
+		"donor_vin":           "jtn bf3ek 0030 12345",
+		"donor_mileage":       "120000",
+		"donor_tested":        "Да",
+		"donor_warranty_days": 14,
+	}
+
+	tests := []struct {
+		name   string
+		labels DonorLocalization
+		pos    position
+		props  Properties
+		want   []xmlParam
+		detail string
+	}{
+		{
+			name:  "Used part",
+			pos:   position{Condition: 30},
+			props: donor,
+			want: []xmlParam{
+				{Name: "Марка", Content: "Toyota"},
+				{Name: "Модель", Content: "Camry"},
+				{Name: "Год выпуска", Content: "2015"},
+				{Name: "VIN", Content: "***********012345"},
+				{Name: "Пробег", Content: "120000 км"},
+				{Name: "Проверено на автомобиле", Content: "да"},
+				{Name: "Гарантия", Content: "14 дн."},
+			},
+			detail: "<p>Автомобиль-донор:</p><ul><li>Марка: Toyota.</li> <li>Модель: Camry.</li> <li>Год выпуска: 2015.</li> " +
+				"<li>VIN: ***********012345.</li> <li>Пробег: 120000 км.</li> <li>Проверено на автомобиле: да.</li> <li>Гарантия: 14 дн..</li></ul> ",
+		},
+		{
+			name:   "Localized labels and partial data",
+			labels: DonorLocalization{DonorTitle: "Donor car", DonorMake: "Make", DonorYear: " "},
+			pos:    position{Condition: 30},
+			props:  Properties{"donor_make": "BMW", "donor_year": "2010", "donor_vin": "1234", "donor_tested": false},
+			want: []xmlParam{
+				{Name: "Make", Content: "BMW"},
+				{Name: "Год выпуска", Content: "2010"},
+				{Name: "VIN", Content: "1234"},
+			},
+			detail: "<p>Donor car:</p><ul><li>Make: BMW.</li> <li>Год выпуска: 2010.</li> <li>VIN: 1234.</li></ul> ",
+		},
+		{
+			name:  "New part ignores donor",
+			props: donor,
+		},
+		{
+			name:  "Used part without donor",
+			pos:   position{Condition: 30},
+			props: Properties{"color": "black"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newDonorLabels(Localization{DonorLocalization: tt.labels})
+			got := l.params(usedDonor(tt.pos, newPropReader(tt.props, "BRAND|NUM", nil)))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("params() mismatch (-want +got):\n%s", diff)
+			}
+			if detail := l.detail(got); detail != tt.detail {
+				t.Errorf("detail() = %q, want %q", detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestDonorDescription(t *testing.T) {
+
+	b := &offerBuilder{avito: &avitoParams{}, donorLabels: newDonorLabels(Localization{})}
+	b.params.Localization = Localization{WearoutPreOwned: "Б/у", ListThBuStatement: "Состояние", StatementTypeNormal: "хорошее"}
+	b.description, b.defaultDescription = loadDescriptionTemplates("", 0)
+
+	pos := position{Brand: "TOYOTA", Number: "1900031", Description: "Двигатель", Condition: 50}
+	props := Properties{"donor_make": "Toyota", "donor_model": "Camry"}
+
+	want := "Бренд: TOYOTA, артикул: 1900031, Б/у. Состояние - хорошее, Двигатель. <br/>" +
+		"<p>Автомобиль-донор:</p><ul><li>Марка: Toyota.</li> <li>Модель: Camry.</li></ul> "
+	if got := b.buildDescription(pos, props, "key", nil, ""); got != want {
+		t.Errorf("buildDescription() = %q, want %q", got, want)
+	}
+
+	var offer xmlOffer
+	setDonorTags(&offer, usedDonor(pos, newPropReader(props, "TOYOTA|1900031", nil)))
+	if offer.Make != "Toyota" || offer.Model != "Camry" || offer.Year != "" {
+		t.Errorf("setDonorTags() = %q, %q, %q", offer.Make, offer.Model, offer.Year)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPositionErrorsLimit содержит допустимое по умолчанию количество позиций,
+// обработка которых завершилась ошибкой, после которого задача считается неуспешной.
+const defaultPositionErrorsLimit = 100
+
+var errPositionErrorsLimit = errors.New("превышено допустимое количество ошибок обработки позиций")
+
+// positionError описывает ошибку, возникшую при обработке позиции прайса.
+type positionError struct {
+	Brand  string
+	Number string
+	Err    string
+	Stack  string
+}
+
+// positionErrorsLimit возвращает допустимое количество ошибок обработки позиций.
+// Если лимит не задан, используется defaultPositionErrorsLimit. Ноль означает,
+// что задача завершается ошибкой на первой же сломанной позиции,
+// отрицательное значение - что количество ошибок не ограничено.
+func (a *avitoParams) positionErrorsLimit() int {
+
+	if a.PositionErrorsLimit == nil {
+		return defaultPositionErrorsLimit
+	}
+
+	return *a.PositionErrorsLimit
+}
+
+// guardPosition вызывает build для позиции pos, перехватывая панику.
+// Паника регистрируется в отчёте задачи как ошибка позиции,
+// а позиция исключается из фида.
+func guardPosition[T any](pos position, report *feedReport, build func(position) (T, bool)) (offer T, ok bool) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			report.addPositionError(positionError{
+				Brand:  pos.Brand,
+				Number: pos.Number,
+				Err:    fmt.Sprint(r),
+				Stack:  string(debug.Stack()),
+			})
+			ok = false
+		}
+	}()
+
+	return build(pos)
+}
+
+// recoverTaskPanic перехватывает панику, возникшую вне обработки позиций,
+// и сохраняет её как ошибку задачи.
+func recoverTaskPanic(taskErr *error) {
+
+	if r := recover(); r != nil {
+		*taskErr = errors.Errorf("паника при формировании предложений: %v", r)
+		log.Errorf("%v\n%s", *taskErr, debug.Stack())
+	}
+}
+
+// drainPositionsOnError вычитывает оставшиеся позиции из канала, если задача завершилась ошибкой.
+// Вызывается отложенно, чтобы ни одна ошибка параметров клиента не оставила отправителя заблокированным.
+func drainPositionsOnError(posChan <-chan []position, taskErr *error) {
+
+	if *taskErr != nil {
+		drainPositions(posChan)
+	}
+}
+
+// drainPositions вычитывает оставшиеся позиции из канала,
+// чтобы не блокировать отправителя после досрочного завершения задачи.
+func drainPositions(posChan <-chan []position) {
+	for range posChan {
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuardPosition(t *testing.T) {
+
+	report := newFeedReport()
+	build := func(pos position) (xmlOffer, bool) {
+		if pos.Number == "BAD" {
+			var props map[string]any
+			props["x"] = 1
+		}
+		return xmlOffer{ID: pos.Number}, true
+	}
+
+	offer, ok := guardPosition(position{Brand: "BRAND", Number: "GOOD"}, report, build)
+	if !ok || offer.ID != "GOOD" {
+		t.Errorf("guardPosition(GOOD) = %q, %v, want %q, true", offer.ID, ok, "GOOD")
+	}
+
+	offer, ok = guardPosition(position{Brand: "BRAND", Number: "BAD"}, report, build)
+	if ok || offer.ID != "" {
+		t.Errorf("guardPosition(BAD) = %q, %v, want empty offer, false", offer.ID, ok)
+	}
+
+	if got := report.positionErrorsCount(); got != 1 {
+		t.Fatalf("positionErrorsCount() = %d, want 1", got)
+	}
+
+	e := report.positionErrors[0]
+	if e.Brand != "BRAND" || e.Number != "BAD" || !strings.Contains(e.Err, "nil map") || e.Stack == "" {
+		t.Errorf("unexpected position error: %+v", e)
+	}
+}
+
+func TestPositionErrorsExceeded(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		errors int
+		limit  int
+		want   bool
+	}{
+		{name: "No errors", errors: 0, limit: 0, want: false},
+		{name: "Within limit", errors: 2, limit: 2, want: false},
+		{name: "Over limit", errors: 3, limit: 2, want: true},
+		{name: "Unlimited", errors: 1000, limit: -1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newFeedReport()
+			for i := 0; i < tt.errors; i++ {
+				report.addPositionError(positionError{})
+			}
+
+			if got := report.positionErrorsExceeded(tt.limit); got != tt.want {
+				t.Errorf("positionErrorsExceeded(%d) = %v, want %v", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPositionErrorsLimit(t *testing.T) {
+
+	limit := func(v int) *int { return &v }
+
+	tests := []struct {
+		name  string
+		limit *int
+		want  int
+	}{
+		{name: "Unset", want: defaultPositionErrorsLimit},
+		{name: "Zero tolerance", limit: limit(0), want: 0},
+		{name: "Custom", limit: limit(5), want: 5},
+		{name: "Unlimited", limit: limit(-1), want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&avitoParams{PositionErrorsLimit: tt.limit}).positionErrorsLimit(); got != tt.want {
+				t.Errorf("positionErrorsLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessXMLDrainsOnConfigError(t *testing.T) {
+
+	PricegenStorage = new(PgStorageStub)
+
+	tests := []struct {
+		name  string
+		avito avitoParams
+	}{
+		{name: "Offer ID collisions", avito: avitoParams{OfferIDCollisions: "unknown"}},
+		{name: "Offer ID template", avito: avitoParams{OfferIDTemplate: "{unknown}"}},
+		{name: "Promotion rules", avito: avitoParams{Promotion: &promotionParams{Rules: []promotionRuleParams{{Weekdays: []int{9}}}}}},
+		{name: "Date schedule", avito: avitoParams{DateEndRule: "tomorrow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := offersTask{id: 1, output: make(chan []xmlOffer)}
+			posCh := make(chan []position)
+
+			sent := make(chan struct{})
+			go func() {
+				for i := 0; i < 3; i++ {
+					posCh <- []position{{Brand: "Bosch", Number: "0986"}}
+				}
+				close(posCh)
+				close(sent)
+			}()
+
+			go task.processXML(posCh, &tt.avito, Params{}, nil, nil, nil)
+			for range task.output {
+			}
+
+			select {
+			case <-sent:
+			case <-time.After(time.Second):
+				t.Fatal("processXML() left the sender blocked")
+			}
+			if task.err == nil {
+				t.Error("processXML() error = nil, want configuration error")
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultImageCheckRate ограничивает количество запросов проверки изображений в секунду.
+	defaultImageCheckRate = 20
+	// defaultImageCheckTTL задаёт срок хранения результата проверки изображения.
+	defaultImageCheckTTL = 24 * time.Hour
+	// imageCheckWorkers ограничивает количество одновременных запросов проверки изображений одной позиции.
+	imageCheckWorkers = 4
+	// imageCheckTimeout ограничивает время одного запроса проверки изображения.
+	imageCheckTimeout = 10 * time.Second
+)
+
+// imageCheckEntry содержит результат проверки изображения.
+type imageCheckEntry struct {
+	OK      bool      `json:"ok"`
+	Checked time.Time `json:"checked"`
+}
+
+// imageCheckCache хранит результаты проверки изображений в файле между циклами формирования фидов.
+// Кэш общий для всех задач процесса, вместе с ним задачи делят и ограничение частоты запросов к хосту изображений.
+type imageCheckCache struct {
+	mu       sync.Mutex
+	path     string
+	entries  map[string]imageCheckEntry
+	dirty    bool
+	limiter  *time.Ticker
+	interval time.Duration
+}
+
+var (
+	sharedImageCheckCache     *imageCheckCache
+	sharedImageCheckCacheOnce sync.Once
+)
+
+// getImageCheckCache возвращает общий кэш проверки изображений, загружая его при первом обращении.
+// Кэш хранится вне временного каталога цикла, который удаляется при каждом запуске.
+func getImageCheckCache() *imageCheckCache {
+
+	sharedImageCheckCacheOnce.Do(func() {
+		sharedImageCheckCache = newImageCheckCache(filepath.Join(os.TempDir(), stageName+"-image-check.json"))
+		if err := sharedImageCheckCache.load(); err != nil {
+			log.Warnf("Не удалось загрузить кэш проверки изображений: %v", err)
+		}
+	})
+
+	return sharedImageCheckCache
+}
+
+func newImageCheckCache(path string) *imageCheckCache {
+	return &imageCheckCache{path: path, entries: make(map[string]imageCheckEntry)}
+}
+
+// rateLimiter возвращает общий для всех пользователей кэша ограничитель частоты запросов.
+// Если задачи указали разную частоту, действует наименьшая из них.
+func (c *imageCheckCache) rateLimiter(rate int) *time.Ticker {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interval := time.Second / time.Duration(rate)
+	switch {
+	case c.limiter == nil:
+		c.limiter = time.NewTicker(interval)
+		c.interval = interval
+	case interval > c.interval:
+		c.limiter.Reset(interval)
+		c.interval = interval
+	}
+
+	return c.limiter
+}
+
+// get возвращает результат проверки изображения url, если он не старше ttl.
+func (c *imageCheckCache) get(url string, ttl time.Duration, now time.Time) (ok, found bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, found := c.entries[url]
+	if !found || now.Sub(e.Checked) > ttl {
+		return false, false
+	}
+
+	return e.OK, true
+}
+
+// set сохраняет результат проверки изображения url.
+func (c *imageCheckCache) set(url string, ok bool, now time.Time) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[url] = imageCheckEntry{OK: ok, Checked: now}
+	c.dirty = true
+}
+
+// load загружает кэш из файла. Отсутствие файла не является ошибкой.
+func (c *imageCheckCache) load() error {
+
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]imageCheckEntry)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return errors.Wrapf(err, "разбор файла %s", c.path)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for url, e := range entries {
+		if _, ok := c.entries[url]; !ok {
+			c.entries[url] = e
+		}
+	}
+
+	return nil
+}
+
+// save сохраняет кэш в файл, удаляя результаты старше ttl.
+// Файл заменяется целиком, чтобы прерванная запись не повредила кэш.
+func (c *imageCheckCache) save(ttl time.Duration, now time.Time) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	for url, e := range c.entries {
+		if now.Sub(e.Checked) > ttl {
+			delete(c.entries, url)
+		}
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+
+	return nil
+}
+
+// imageChecker проверяет доступность изображений HEAD-запросами к хосту изображений.
+// Запросы выполняются параллельно с ограничением частоты, результаты кэшируются.
+type imageChecker struct {
+	client  *http.Client
+	cache   *imageCheckCache
+	ttl     time.Duration
+	limiter *time.Ticker
+	now     func() time.Time
+}
+
+// newImageChecker создаёт проверку изображений с частотой не более rate запросов в секунду
+// и сроком хранения результатов ttl. Нулевые значения заменяются значениями по умолчанию.
+// Ограничение частоты общее для всех проверок, использующих кэш cache.
+func newImageChecker(cache *imageCheckCache, rate int, ttl time.Duration) *imageChecker {
+
+	if rate <= 0 {
+		rate = defaultImageCheckRate
+	}
+	if ttl <= 0 {
+		ttl = defaultImageCheckTTL
+	}
+
+	return &imageChecker{
+		client:  &http.Client{Timeout: imageCheckTimeout},
+		cache:   cache,
+		ttl:     ttl,
+		limiter: cache.rateLimiter(rate),
+		now:     time.Now,
+	}
+}
+
+// close сохраняет кэш. Общий ограничитель частоты не останавливается,
+// так как им продолжают пользоваться другие задачи.
+func (c *imageChecker) close() {
+
+	if c == nil {
+		return
+	}
+
+	if err := c.cache.save(c.ttl, c.now()); err != nil {
+		log.Warnf("Не удалось сохранить кэш проверки изображений: %v", err)
+	}
+}
+
+// filter возвращает доступные изображения из images в исходном порядке.
+// Недоступные изображения регистрируются в отчёте задачи для позиции key.
+func (c *imageChecker) filter(images []xmlImage, key string, report *feedReport) []xmlImage {
+
+	if c == nil || len(images) == 0 {
+		return images
+	}
+
+	available := make([]bool, len(images))
+	sem := make(chan struct{}, imageCheckWorkers)
+	var wg sync.WaitGroup
+
+	for i, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			available[i] = c.available(url)
+		}(i, img.URL)
+	}
+	wg.Wait()
+
+	out := make([]xmlImage, 0, len(images))
+	for i, img := range images {
+		if available[i] {
+			out = append(out, img)
+			continue
+		}
+		report.addMissingImage(key, img.URL)
+	}
+
+	return out
+}
+
+// available сообщает, доступно ли изображение url.
+// Если хост изображений не ответил, вернул ошибку сервера или ограничил частоту запросов, изображение считается доступным,
+// а результат не кэшируется.
+func (c *imageChecker) available(url string) bool {
+
+	if ok, found := c.cache.get(url, c.ttl, c.now()); found {
+		return ok
+	}
+
+	<-c.limiter.C
+
+	ctx, cancel := context.WithTimeout(context.Background(), imageCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		c.cache.set(url, false, c.now())
+		return false
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Debugf("Не удалось проверить изображение %s: %v", url, err)
+		return true
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		c.cache.set(url, true, c.now())
+		return true
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	default:
+		c.cache.set(url, false, c.now())
+		return false
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// imageHost имитирует хост изображений и считает HEAD-запросы по путям.
+type imageHost struct {
+	mu       sync.Mutex
+	requests map[string]int
+	status   map[string]int
+}
+
+func (h *imageHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	h.mu.Lock()
+	h.requests[r.URL.Path]++
+	status, ok := h.status[r.URL.Path]
+	h.mu.Unlock()
+
+	if r.Method != http.MethodHead {
+		status = http.StatusMethodNotAllowed
+	} else if !ok {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+func (h *imageHost) count(path string) int {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.requests[path]
+}
+
+func TestImageCheckerFilter(t *testing.T) {
+
+	host := &imageHost{
+		requests: make(map[string]int),
+		status: map[string]int{
+			"/missing.jpg": http.StatusNotFound,
+			"/error.jpg":   http.StatusInternalServerError,
+			"/limited.jpg": http.StatusTooManyRequests,
+		},
+	}
+	srv := httptest.NewServer(host)
+	defer srv.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	checker := newImageChecker(newImageCheckCache(cachePath), 1000, time.Hour)
+	checker.now = func() time.Time { return now }
+
+	images := []xmlImage{
+		{URL: srv.URL + "/ok1.jpg"},
+		{URL: srv.URL + "/missing.jpg"},
+		{URL: srv.URL + "/ok2.jpg"},
+		{URL: srv.URL + "/error.jpg"},
+		{URL: srv.URL + "/limited.jpg"},
+		{URL: "://bad"},
+	}
+	want := []xmlImage{
+		{URL: srv.URL + "/ok1.jpg"},
+		{URL: srv.URL + "/ok2.jpg"},
+		{URL: srv.URL + "/error.jpg"},
+		{URL: srv.URL + "/limited.jpg"},
+	}
+
+	report := newFeedReport()
+	if got := checker.filter(images, "BRAND|NUM", report); !cmp.Equal(got, want) {
+		t.Errorf("filter() = %v, want %v", got, want)
+	}
+	if len(report.missingImages) != 2 {
+		t.Errorf("missingImages = %v, want 2 entries", report.missingImages)
+	}
+
+	// Повторная проверка использует кэш, кроме неокончательных результатов.
+	checker.filter(images, "BRAND|NUM", nil)
+	for path, want := range map[string]int{"/ok1.jpg": 1, "/missing.jpg": 1, "/error.jpg": 2, "/limited.jpg": 2} {
+		if got := host.count(path); got != want {
+			t.Errorf("requests %s = %d, want %d", path, got, want)
+		}
+	}
+
+	// Результаты сохраняются в файл и загружаются новой проверкой.
+	checker.close()
+	cache := newImageCheckCache(cachePath)
+	if err := cache.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	reloaded := newImageChecker(cache, 1000, time.Hour)
+	defer reloaded.close()
+	reloaded.now = func() time.Time { return now.Add(30 * time.Minute) }
+
+	reloaded.filter(images[:2], "BRAND|NUM", nil)
+	if got := host.count("/ok1.jpg"); got != 1 {
+		t.Errorf("requests after reload = %d, want 1", got)
+	}
+
+	// Результаты старше TTL проверяются заново.
+	reloaded.now = func() time.Time { return now.Add(2 * time.Hour) }
+	reloaded.filter(images[:2], "BRAND|NUM", nil)
+	if got := host.count("/ok1.jpg"); got != 2 {
+		t.Errorf("requests after TTL = %d, want 2", got)
+	}
+}
+
+func TestImageCheckerRateLimit(t *testing.T) {
+
+	host := &imageHost{requests: make(map[string]int)}
+	srv := httptest.NewServer(host)
+	defer srv.Close()
+
+	checker := newImageChecker(newImageCheckCache(filepath.Join(t.TempDir(), "cache.json")), 50, time.Hour)
+	defer checker.close()
+
+	images := make([]xmlImage, 0, 10)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		images = append(images, xmlImage{URL: srv.URL + "/" + name + ".jpg"})
+	}
+
+	start := time.Now()
+	checker.filter(images, "BRAND|NUM", nil)
+
+	// 10 запросов с частотой 50 в секунду занимают не меньше 180 мс.
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("filter() took %v, want at least 180ms", elapsed)
+	}
+}
+
+func TestImageCheckerSharedRateLimit(t *testing.T) {
+
+	host := &imageHost{requests: make(map[string]int)}
+	srv := httptest.NewServer(host)
+	defer srv.Close()
+
+	cache := newImageCheckCache(filepath.Join(t.TempDir(), "cache.json"))
+	fast := newImageChecker(cache, 1000, time.Hour)
+	defer fast.close()
+	slow := newImageChecker(cache, 50, time.Hour)
+	defer slow.close()
+
+	if fast.limiter != slow.limiter {
+		t.Fatal("checkers with the same cache use different limiters")
+	}
+
+	images := make([]xmlImage, 0, 10)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		images = append(images, xmlImage{URL: srv.URL + "/" + name + ".jpg"})
+	}
+
+	start := time.Now()
+	fast.filter(images, "BRAND|NUM", nil)
+
+	// Общий ограничитель работает с наименьшей из заданных частот.
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("filter() took %v, want at least 180ms", elapsed)
+	}
+}
+
+func TestBuildImagesLimitAfterCheck(t *testing.T) {
+
+	host := &imageHost{
+		requests: make(map[string]int),
+		status:   map[string]int{"/BOSCH/a.jpg": http.StatusNotFound},
+	}
+	srv := httptest.NewServer(host)
+	defer srv.Close()
+
+	avito := avitoParams{ImageProvider: imageProviderPattern, ImageURLPattern: srv.URL + "/{brand}/{name}", ImagePolicy: &imagePolicyParams{MaxCount: 2}}
+	b := &offerBuilder{avito: &avito, imagePolicy: newImagePolicy(&avito), report: newFeedReport()}
+
+	var err error
+	if b.imageProvider, b.placeholderProvider, err = newImageURLProviders(&avito, ""); err != nil {
+		t.Fatalf("newImageURLProviders() error = %v", err)
+	}
+	b.imageChecker = newImageChecker(newImageCheckCache(filepath.Join(t.TempDir(), "cache.json")), 1000, time.Hour)
+	defer b.imageChecker.close()
+
+	props := Properties{"images": []any{"a.jpg", "b.jpg", "c.jpg", "d.jpg"}}
+	got := b.buildImages(position{Brand: "BOSCH"}, newPropReader(props, "BOSCH|1", nil), "BOSCH|1")
+
+	// Недоступное изображение не занимает место в пределах MaxCount.
+	want := []xmlImage{{URL: srv.URL + "/BOSCH/b.jpg"}, {URL: srv.URL + "/BOSCH/c.jpg"}}
+	if !cmp.Equal(got, want) {
+		t.Errorf("buildImages() = %v, want %v", got, want)
+	}
+}
+
+func TestNilImageChecker(t *testing.T) {
+
+	var checker *imageChecker
+	images := []xmlImage{{URL: "https://example.com/1.jpg"}}
+
+	if got := checker.filter(images, "BRAND|NUM", nil); !cmp.Equal(got, images) {
+		t.Errorf("filter() = %v, want %v", got, images)
+	}
+	checker.close()
+}
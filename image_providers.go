@@ -0,0 +1,288 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"gitlab.nodasoft.com/pricegen/avito/imagename"
+)
+
+// Встроенные поставщики ссылок на изображения.
+const (
+	imageProviderAuto    = ""        // autorus при заданном AlternativeImageProxy, иначе pubimg
+	imageProviderPubimg  = "pubimg"  // https://pubimg.nodacdn.net/images/
+	imageProviderAutorus = "autorus" // {AlternativeImageProxy}/images/{brand}/{number}/full/{name}
+	imageProviderPattern = "pattern" // шаблон клиента ImageURLPattern
+)
+
+// pubimgBaseURL содержит адрес хоста изображений по умолчанию.
+const pubimgBaseURL = "https://pubimg.nodacdn.net/images/"
+
+// ImageRef описывает изображение позиции.
+type ImageRef struct {
+	Name   string // имя файла изображения из article api или фотографии б/у товара
+	Brand  string
+	Number string
+}
+
+// ImageURLProvider формирует ссылки на изображения для тега <Images>.
+type ImageURLProvider interface {
+	// ImageURL возвращает ссылку на изображение ref.
+	ImageURL(ref ImageRef) (*url.URL, error)
+	// PlaceholderURL возвращает ссылку на заглушку для позиции без изображений.
+	// ref.Name содержит имя заглушки клиента или пустую строку для встроенной заглушки.
+	// Возвращает false, если заглушку сформировать нельзя.
+	PlaceholderURL(ref ImageRef) (*url.URL, bool)
+}
+
+// ImageURLProviderFactory создаёт поставщика ссылок на изображения по параметрам клиента.
+type ImageURLProviderFactory func(avito *avitoParams, pfx string) (ImageURLProvider, error)
+
+var (
+	imageURLProvidersMu sync.RWMutex
+	imageURLProviders   = map[string]ImageURLProviderFactory{
+		imageProviderPubimg:  newPubimgProvider,
+		imageProviderAutorus: newAutorusProvider,
+		imageProviderPattern: newPatternProvider,
+	}
+)
+
+// RegisterImageURLProvider регистрирует поставщика ссылок на изображения name,
+// которого клиент может выбрать параметром ImageProvider.
+func RegisterImageURLProvider(name string, factory ImageURLProviderFactory) {
+
+	imageURLProvidersMu.Lock()
+	defer imageURLProvidersMu.Unlock()
+
+	imageURLProviders[name] = factory
+}
+
+// newImageURLProviders возвращает поставщиков ссылок на изображения и заглушки для клиента.
+// Ссылки альтернативного прокси подписываются при заданном ImageSignSecret (см. signedImageProvider).
+// При автоматическом выборе заглушки, как и раньше, формируются альтернативным прокси,
+// даже если для изображений он отключён параметром DisableAlternativeImage.
+func newImageURLProviders(avito *avitoParams, pfx string) (images, placeholders ImageURLProvider, err error) {
+
+	name := avito.ImageProvider
+	if name == imageProviderAuto {
+		name = imageProviderPubimg
+		if avito.AlternativeImageProxy != "" && !avito.DisableAlternativeImage {
+			name = imageProviderAutorus
+		}
+	}
+
+	imageURLProvidersMu.RLock()
+	factory, ok := imageURLProviders[name]
+	names := sortedKeys(imageURLProviders)
+	imageURLProvidersMu.RUnlock()
+
+	if !ok {
+		return nil, nil, errors.Errorf("неизвестный поставщик изображений %q, доступны: %s", name, strings.Join(names, ", "))
+	}
+
+	if images, err = factory(avito, pfx); err != nil {
+		return nil, nil, errors.Wrapf(err, "поставщик изображений %s", name)
+	}
+
+	placeholders = images
+	if avito.ImageProvider == imageProviderAuto && avito.AlternativeImageProxy != "" {
+		if placeholders, err = newAutorusProvider(avito, pfx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if images, err = newSignedImageProvider(images, avito); err != nil {
+		return nil, nil, err
+	}
+	if placeholders, err = newSignedImageProvider(placeholders, avito); err != nil {
+		return nil, nil, err
+	}
+
+	return images, placeholders, nil
+}
+
+// appendImageQuery добавляет к ссылке на изображение параметр без значения ("?1", "?3&1").
+// Параметры добавляются к существующему запросу, поэтому второй "?" не появляется.
+func appendImageQuery(u *url.URL, token string) {
+
+	token = url.QueryEscape(token)
+	if u.RawQuery == "" {
+		u.RawQuery = token
+		return
+	}
+	u.RawQuery += "&" + token
+}
+
+// newImageNameEncoder возвращает кодирование имён изображений по ImageNameVersion и ImageNameSeed клиента.
+func newImageNameEncoder(avito *avitoParams, pfx string) (imagename.Encoder, error) {
+	return imagename.NewEncoder(avito.ImageNameVersion, avito.ImageNameSeed, pfx)
+}
+
+// pubimgProvider формирует ссылки на хост изображений pubimg.
+// Имя файла кодируется версией ImageNameVersion клиента (см. пакет imagename).
+type pubimgProvider struct {
+	base  string
+	names imagename.Encoder
+}
+
+func newPubimgProvider(avito *avitoParams, pfx string) (ImageURLProvider, error) {
+
+	names, err := newImageNameEncoder(avito, pfx)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubimgProvider{base: pubimgBaseURL, names: names}, nil
+}
+
+func (p pubimgProvider) ImageURL(ref ImageRef) (*url.URL, error) {
+
+	if strings.Contains(ref.Name, "http://") || strings.Contains(ref.Name, "https://") {
+		return url.Parse(ref.Name)
+	}
+
+	return url.Parse(p.base + p.names.Encode(ref.Name))
+}
+
+// PlaceholderURL формирует ссылку на заглушку клиента; встроенной заглушки у pubimg нет.
+func (p pubimgProvider) PlaceholderURL(ref ImageRef) (*url.URL, bool) {
+	return placeholderByName(p, ref)
+}
+
+// autorusProvider формирует ссылки альтернативного прокси по документации autorus.ru.
+type autorusProvider struct {
+	proxy            string
+	placeholder      string
+	updatePhoto      bool
+	updatePhotoCount int
+}
+
+// autorusPlaceholders содержит имена заглушек альтернативного прокси по AlternativeImageRequestMethod.
+var autorusPlaceholders = map[string]string{
+	"":        "05c40c050e1eeef58efb8bcf8e6ce2510b.png",
+	"URL-JPG": "1149f97a082eb731bab1e4d0bb281be3e8.jpg",
+}
+
+func newAutorusProvider(avito *avitoParams, _ string) (ImageURLProvider, error) {
+
+	if avito.AlternativeImageProxy == "" {
+		return nil, errors.New("не задан AlternativeImageProxy")
+	}
+
+	placeholder, ok := autorusPlaceholders[avito.AlternativeImageRequestMethod]
+	if !ok {
+		placeholder = autorusPlaceholders[""]
+	}
+
+	return autorusProvider{
+		proxy:            strings.TrimSuffix(avito.AlternativeImageProxy, "/"),
+		placeholder:      placeholder,
+		updatePhoto:      avito.UpdatePhoto,
+		updatePhotoCount: avito.UpdatePhotoCount,
+	}, nil
+}
+
+func (p autorusProvider) ImageURL(ref ImageRef) (*url.URL, error) {
+
+	brand := strings.Replace(url.PathEscape(replaceModAutorus(ref.Brand, false)), "+", "%20", -1)
+	number := url.PathEscape(replaceModAutorus(ref.Number, true))
+
+	u, err := url.Parse(p.proxy + "/images/" + brand + "/" + number + "/full/" + ref.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.updatePhoto || p.updatePhotoCount != 0 {
+		appendImageQuery(u, strconv.Itoa(p.updatePhotoCount))
+	}
+
+	return u, nil
+}
+
+func (p autorusProvider) PlaceholderURL(ref ImageRef) (*url.URL, bool) {
+
+	name := ref.Name
+	if name == "" {
+		name = p.placeholder
+	}
+
+	u, err := url.Parse(p.proxy + "/images/" + strings.ToLower(replaceModAutorus(ref.Brand, false)) + "/" +
+		strings.ToLower(replaceModAutorus(ref.Number, true)) + "/full/" + name)
+	if err != nil {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// patternProvider формирует ссылки по шаблону клиента ImageURLPattern, например
+// "https://cdn.example.com/{brand}/{number}/{name}".
+// Подстановки {brand}, {number}, {name} и {encoded} (закодированное имя с постфиксом, см. пакет imagename)
+// экранируются для пути. Если имя изображения - полная ссылка, она используется без изменений.
+type patternProvider struct {
+	pattern string
+	names   imagename.Encoder
+}
+
+// imagePatternFields содержит подстановки шаблона ImageURLPattern.
+var imagePatternFields = []string{"{brand}", "{number}", "{name}", "{encoded}"}
+
+func newPatternProvider(avito *avitoParams, pfx string) (ImageURLProvider, error) {
+
+	pattern := strings.TrimSpace(avito.ImageURLPattern)
+	if !strings.Contains(pattern, "{name}") && !strings.Contains(pattern, "{encoded}") {
+		return nil, errors.Errorf("шаблон ImageURLPattern %q не содержит {name} или {encoded}", pattern)
+	}
+
+	sample := pattern
+	for _, field := range imagePatternFields {
+		sample = strings.ReplaceAll(sample, field, "x")
+	}
+	if u, err := url.Parse(sample); err != nil || !u.IsAbs() || u.Host == "" {
+		return nil, errors.Errorf("шаблон ImageURLPattern %q не является абсолютной ссылкой", pattern)
+	}
+
+	names, err := newImageNameEncoder(avito, pfx)
+	if err != nil {
+		return nil, err
+	}
+
+	return patternProvider{pattern: pattern, names: names}, nil
+}
+
+func (p patternProvider) ImageURL(ref ImageRef) (*url.URL, error) {
+
+	if strings.Contains(ref.Name, "http://") || strings.Contains(ref.Name, "https://") {
+		return url.Parse(ref.Name)
+	}
+
+	r := strings.NewReplacer(
+		"{brand}", url.PathEscape(ref.Brand),
+		"{number}", url.PathEscape(ref.Number),
+		"{name}", url.PathEscape(ref.Name),
+		"{encoded}", url.PathEscape(p.names.Encode(ref.Name)),
+	)
+
+	return url.Parse(r.Replace(p.pattern))
+}
+
+// PlaceholderURL формирует ссылку на заглушку клиента по шаблону; встроенной заглушки нет.
+func (p patternProvider) PlaceholderURL(ref ImageRef) (*url.URL, bool) {
+	return placeholderByName(p, ref)
+}
+
+// placeholderByName формирует ссылку на заглушку клиента ref.Name как на обычное изображение.
+func placeholderByName(p ImageURLProvider, ref ImageRef) (*url.URL, bool) {
+
+	if ref.Name == "" {
+		return nil, false
+	}
+
+	u, err := p.ImageURL(ref)
+
+	return u, err == nil
+}
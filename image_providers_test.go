@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+// cdnProvider используется для проверки регистрации поставщика клиента.
+type cdnProvider struct{ host string }
+
+func (p cdnProvider) ImageURL(ref ImageRef) (*url.URL, error) {
+	return &url.URL{Scheme: "https", Host: p.host, Path: "/" + ref.Brand + "/" + ref.Name}, nil
+}
+
+func (p cdnProvider) PlaceholderURL(ref ImageRef) (*url.URL, bool) {
+	return &url.URL{Scheme: "https", Host: p.host, Path: "/empty.png"}, true
+}
+
+func TestImageURLProviders(t *testing.T) {
+
+	RegisterImageURLProvider("cdn", func(avito *avitoParams, _ string) (ImageURLProvider, error) {
+		return cdnProvider{host: "cdn.example.com"}, nil
+	})
+
+	const name = "1149f97a082eb731bab1e4d0bb281be3e8.jpg"
+	ref := ImageRef{Name: name, Brand: "Mann Filter", Number: "W 712/95"}
+
+	tests := []struct {
+		name            string
+		avito           avitoParams
+		imageInc        int
+		wantImage       string
+		wantPlaceholder string
+	}{
+		{
+			name:      "Pubimg by default",
+			avito:     avitoParams{},
+			imageInc:  2,
+			wantImage: "https://pubimg.nodacdn.net/images/1149f927a082e5b731baeb1e4d02bb281be3e80005.jpg?2",
+		},
+		{
+			name:            "Autorus with update counter and image inc",
+			avito:           avitoParams{AlternativeImageProxy: "https://img.autorus.ru/", UpdatePhoto: true, UpdatePhotoCount: 3},
+			imageInc:        1,
+			wantImage:       "https://img.autorus.ru/images/mann%20filter/w712-95/full/" + name + "?3&1",
+			wantPlaceholder: "https://img.autorus.ru/images/mann%20filter/w712-95/full/05c40c050e1eeef58efb8bcf8e6ce2510b.png?1",
+		},
+		{
+			name:            "Disabled proxy keeps placeholder",
+			avito:           avitoParams{AlternativeImageProxy: "https://proxy", AlternativeImageRequestMethod: "URL-JPG", DisableAlternativeImage: true},
+			wantImage:       "https://pubimg.nodacdn.net/images/1149f927a082e5b731baeb1e4d02bb281be3e80005.jpg",
+			wantPlaceholder: "https://proxy/images/mann%20filter/w712-95/full/1149f97a082eb731bab1e4d0bb281be3e8.jpg",
+		},
+		{
+			name:      "Pattern",
+			avito:     avitoParams{ImageProvider: "pattern", ImageURLPattern: "https://cdn.example.com/i/{brand}/{number}/{name}?w=800"},
+			imageInc:  1,
+			wantImage: "https://cdn.example.com/i/Mann%20Filter/W%20712%2F95/" + name + "?w=800&1",
+		},
+		{
+			name:            "Registered provider",
+			avito:           avitoParams{ImageProvider: "cdn"},
+			wantImage:       "https://cdn.example.com/Mann%20Filter/" + name,
+			wantPlaceholder: "https://cdn.example.com/empty.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &offerBuilder{avito: &tt.avito, params: Params{ImageInc: tt.imageInc}, pfx: "0005"}
+
+			var err error
+			b.imageProvider, b.placeholderProvider, err = newImageURLProviders(&tt.avito, b.pfx)
+			if err != nil {
+				t.Fatalf("newImageURLProviders() error = %v", err)
+			}
+
+			u, err := b.imageProvider.ImageURL(ref)
+			if err != nil {
+				t.Fatalf("ImageURL() error = %v", err)
+			}
+			if got := b.imageURL(u); got != tt.wantImage {
+				t.Errorf("ImageURL() = %q, want %q", got, tt.wantImage)
+			}
+
+			got := ""
+			if u, ok := b.placeholderProvider.PlaceholderURL(ImageRef{Brand: ref.Brand, Number: ref.Number}); ok {
+				got = b.imageURL(u)
+			}
+			if got != tt.wantPlaceholder {
+				t.Errorf("PlaceholderURL() = %q, want %q", got, tt.wantPlaceholder)
+			}
+		})
+	}
+}
+
+func TestImageURLProvidersErrors(t *testing.T) {
+
+	tests := []avitoParams{
+		{ImageProvider: "unknown"},
+		{ImageProvider: "autorus"},
+		{ImageProvider: "pattern", ImageURLPattern: "https://cdn.example.com/{brand}"},
+		{ImageProvider: "pattern", ImageURLPattern: "/relative/{name}"},
+	}
+
+	for _, avito := range tests {
+		if _, _, err := newImageURLProviders(&avito, "0005"); err == nil {
+			t.Errorf("newImageURLProviders(%+v) error = nil, want error", avito)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Источники версий изображений для обновления фотографий на Авито.
+const (
+	imageRefreshManual   = ""         // только ImageInc и UpdatePhotoCount
+	imageRefreshStorage  = "storage"  // таблица imageVersionsTable хранилища
+	imageRefreshManifest = "manifest" // локальный файл ImageManifest
+)
+
+// imageVersionsTable содержит хеши содержимого или даты изменения изображений по именам файлов.
+const imageVersionsTable = "ImageVersion"
+
+// imageVersionTokenLen содержит длину параметра версии в ссылке на изображение.
+const imageVersionTokenLen = 8
+
+// imageVersions содержит параметры версий изображений по именам файлов.
+// Параметр меняется только при изменении хеша или даты изображения, поэтому
+// Авито заново загружает только изменённые фотографии, а не весь фид.
+type imageVersions map[string]string
+
+// newImageVersions загружает версии изображений из источника ImageRefresh клиента.
+func newImageVersions(avito *avitoParams) (imageVersions, error) {
+
+	switch avito.ImageRefresh {
+	case imageRefreshManual:
+		return nil, nil
+	case imageRefreshStorage:
+		return newImageVersionsFrom(PricegenStorage.GetAvitoSpec(imageVersionsTable, "version")), nil
+	case imageRefreshManifest:
+		return loadImageManifest(avito.ImageManifest)
+	default:
+		return nil, errors.Errorf("неизвестный источник версий изображений %q", avito.ImageRefresh)
+	}
+}
+
+// loadImageManifest загружает версии изображений из JSON-файла вида {"имя файла": "хеш или дата изменения"}.
+func loadImageManifest(path string) (imageVersions, error) {
+
+	if path == "" {
+		return nil, errors.New("не задан ImageManifest")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "чтение манифеста изображений")
+	}
+
+	stored := make(map[string]string)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, errors.Wrapf(err, "разбор манифеста изображений %s", path)
+	}
+
+	return newImageVersionsFrom(stored), nil
+}
+
+// newImageVersionsFrom возвращает параметры версий для хешей или дат изменения stored.
+func newImageVersionsFrom(stored map[string]string) imageVersions {
+
+	v := make(imageVersions, len(stored))
+	for name, version := range stored {
+		name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+		if name == "" || version == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(version))
+		v[name] = hex.EncodeToString(sum[:])[:imageVersionTokenLen]
+	}
+
+	return v
+}
+
+// apply добавляет к ссылке u параметр версии изображения name, если версия известна.
+func (v imageVersions) apply(u *url.URL, name string) {
+
+	if token, ok := v[name]; ok {
+		appendImageQuery(u, token)
+	}
+}
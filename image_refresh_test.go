@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageVersions(t *testing.T) {
+
+	before := newImageVersionsFrom(map[string]string{
+		"a.jpg": "2026-10-01T10:00:00Z",
+		"b.jpg": "5d41402abc4b2a76b9719d911017c592",
+		" ":     "ignored",
+	})
+	after := newImageVersionsFrom(map[string]string{
+		"a.jpg": "2026-10-18T12:00:00Z",
+		"b.jpg": "5d41402abc4b2a76b9719d911017c592",
+	})
+
+	urls := func(v imageVersions) map[string]string {
+		res := make(map[string]string)
+		for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+			u, _ := url.Parse("https://img.example.com/" + name + "?3")
+			v.apply(u, name)
+			res[name] = u.String()
+		}
+		return res
+	}
+
+	old, cur := urls(before), urls(after)
+	if old["a.jpg"] == cur["a.jpg"] {
+		t.Errorf("changed image URL = %q, want new version", cur["a.jpg"])
+	}
+	if old["b.jpg"] != cur["b.jpg"] {
+		t.Errorf("unchanged image URL = %q, want %q", cur["b.jpg"], old["b.jpg"])
+	}
+	if want := "https://img.example.com/c.jpg?3"; cur["c.jpg"] != want {
+		t.Errorf("unknown image URL = %q, want %q", cur["c.jpg"], want)
+	}
+	if len(before) != 2 {
+		t.Errorf("newImageVersionsFrom() = %v, want 2 entries", before)
+	}
+
+	var none imageVersions
+	u, _ := url.Parse("https://img.example.com/a.jpg")
+	none.apply(u, "a.jpg")
+	if u.RawQuery != "" {
+		t.Errorf("apply() without versions = %q", u.String())
+	}
+}
+
+func TestNewImageVersions(t *testing.T) {
+
+	PricegenStorage = new(PgStorageStub)
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(manifest, []byte(`{"a.jpg": "etag-1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`[`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		avito   avitoParams
+		wantLen int
+		wantErr bool
+	}{
+		{name: "Manual", avito: avitoParams{}},
+		{name: "Storage", avito: avitoParams{ImageRefresh: imageRefreshStorage}},
+		{name: "Manifest", avito: avitoParams{ImageRefresh: imageRefreshManifest, ImageManifest: manifest}, wantLen: 1},
+		{name: "Missing manifest", avito: avitoParams{ImageRefresh: imageRefreshManifest, ImageManifest: filepath.Join(dir, "none.json")}, wantErr: true},
+		{name: "Broken manifest", avito: avitoParams{ImageRefresh: imageRefreshManifest, ImageManifest: broken}, wantErr: true},
+		{name: "Manifest without path", avito: avitoParams{ImageRefresh: imageRefreshManifest}, wantErr: true},
+		{name: "Unknown source", avito: avitoParams{ImageRefresh: "etag"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newImageVersions(&tt.avito)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newImageVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("newImageVersions() = %v, want %d entries", got, tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.nodasoft.com/pricegen/avito/imagesign"
+)
+
+const (
+	// defaultImageSignKeyVersion используется, если версия ключа подписи не задана.
+	defaultImageSignKeyVersion = 1
+	// defaultImageSignPeriod содержит период смены срока действия подписанных ссылок в днях.
+	defaultImageSignPeriod = 30
+)
+
+// signedImageProvider подписывает ссылки поставщика provider на хосте альтернативного прокси.
+// Ссылки на другие хосты (pubimg, полные ссылки из фотографий б/у товаров) не подписываются.
+type signedImageProvider struct {
+	provider ImageURLProvider
+	host     string
+	key      imagesign.Key
+	expires  time.Time
+}
+
+// newSignedImageProvider возвращает provider с подписью ссылок, если клиенту задан ImageSignSecret.
+// Срок действия ссылок вычисляется один раз для задачи, см. imagesign.Expiry.
+func newSignedImageProvider(provider ImageURLProvider, avito *avitoParams) (ImageURLProvider, error) {
+
+	if avito.ImageSignSecret == "" {
+		return provider, nil
+	}
+
+	u, err := url.Parse(avito.AlternativeImageProxy)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("подпись ссылок требует AlternativeImageProxy, задан %q", avito.AlternativeImageProxy)
+	}
+	if strings.TrimSpace(avito.ImageSignKeyID) == "" {
+		return nil, errors.New("подпись ссылок требует ImageSignKeyID")
+	}
+
+	version := avito.ImageSignKeyVersion
+	if version == 0 {
+		version = defaultImageSignKeyVersion
+	}
+	period := avito.ImageSignPeriod
+	if period <= 0 {
+		period = defaultImageSignPeriod
+	}
+
+	return signedImageProvider{
+		provider: provider,
+		host:     u.Host,
+		key: imagesign.Key{
+			KeyID:  imagesign.KeyID{Client: strings.TrimSpace(avito.ImageSignKeyID), Version: version},
+			Secret: avito.ImageSignSecret,
+		},
+		expires: imagesign.Expiry(time.Now(), time.Duration(period)*24*time.Hour),
+	}, nil
+}
+
+func (p signedImageProvider) ImageURL(ref ImageRef) (*url.URL, error) {
+
+	u, err := p.provider.ImageURL(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.sign(u), nil
+}
+
+func (p signedImageProvider) PlaceholderURL(ref ImageRef) (*url.URL, bool) {
+
+	u, ok := p.provider.PlaceholderURL(ref)
+	if !ok {
+		return nil, false
+	}
+
+	return p.sign(u), true
+}
+
+func (p signedImageProvider) sign(u *url.URL) *url.URL {
+
+	if strings.EqualFold(u.Host, p.host) {
+		imagesign.Sign(u, p.key, p.expires)
+	}
+
+	return u
+}
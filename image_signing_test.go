@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.nodasoft.com/pricegen/avito/imagesign"
+)
+
+func TestSignedImageURLs(t *testing.T) {
+
+	avito := &avitoParams{AlternativeImageProxy: "https://img.autorus.ru", ImageSignSecret: "secret", ImageSignKeyID: "client-a", UpdatePhoto: true, UpdatePhotoCount: 3}
+	ref := ImageRef{Name: "photo.jpg", Brand: "Mann Filter", Number: "W 712/95"}
+
+	images, placeholders, err := newImageURLProviders(avito, "0005")
+	if err != nil {
+		t.Fatalf("newImageURLProviders() error = %v", err)
+	}
+
+	u, err := images.ImageURL(ref)
+	if err != nil {
+		t.Fatalf("ImageURL() error = %v", err)
+	}
+
+	b := &offerBuilder{params: Params{ImageInc: 1}}
+	withInc := b.imageURL(u)
+	if !strings.HasPrefix(withInc, "https://img.autorus.ru/images/mann%20filter/w712-95/full/photo.jpg?3&exp=") {
+		t.Errorf("ImageURL() = %q, want signed proxy URL", withInc)
+	}
+	if q := u.Query(); q.Get(imagesign.KeyIDParam) != "client-a" || q.Get(imagesign.VersionParam) != "1" {
+		t.Errorf("ImageURL() = %q, want client key", withInc)
+	}
+	current := imagesign.KeyID{Client: "client-a", Version: defaultImageSignKeyVersion}
+	if err := imagesign.Verify(withInc, map[imagesign.KeyID]string{current: "secret"}, time.Now()); err != nil {
+		t.Errorf("imagesign.Verify() error = %v", err)
+	}
+
+	// Ссылка действует не меньше периода ImageSignPeriod.
+	exp := time.Now().Add(defaultImageSignPeriod * 24 * time.Hour)
+	if err := imagesign.Verify(withInc, map[imagesign.KeyID]string{current: "secret"}, exp); err != nil {
+		t.Errorf("imagesign.Verify() within period error = %v", err)
+	}
+
+	// Отзыв ссылок сменой версии ключа.
+	avito.ImageSignSecret, avito.ImageSignKeyVersion = "rotated", 2
+	rotated, _, err := newImageURLProviders(avito, "0005")
+	if err != nil {
+		t.Fatalf("newImageURLProviders() error = %v", err)
+	}
+	u, _ = rotated.ImageURL(ref)
+	rotatedKeys := map[imagesign.KeyID]string{{Client: "client-a", Version: 2}: "rotated"}
+	if err := imagesign.Verify(u.String(), rotatedKeys, time.Now()); err != nil {
+		t.Errorf("imagesign.Verify() rotated error = %v", err)
+	}
+	if err := imagesign.Verify(withInc, rotatedKeys, time.Now()); err != imagesign.ErrUnknownKey {
+		t.Errorf("imagesign.Verify() revoked error = %v, want %v", err, imagesign.ErrUnknownKey)
+	}
+
+	u, ok := placeholders.PlaceholderURL(ImageRef{Brand: ref.Brand, Number: ref.Number})
+	if !ok || u.Query().Get(imagesign.SignatureParam) == "" {
+		t.Errorf("PlaceholderURL() = %v, want signed URL", u)
+	}
+
+	u, _ = url.Parse("https://pubimg.nodacdn.net/images/photo.jpg")
+	if got := images.(signedImageProvider).sign(u).String(); got != "https://pubimg.nodacdn.net/images/photo.jpg" {
+		t.Errorf("sign() = %q, want other hosts unsigned", got)
+	}
+}
+
+func TestSignedImageProviderParams(t *testing.T) {
+
+	invalid := []avitoParams{
+		{ImageSignSecret: "secret", ImageSignKeyID: "client-a"},
+		{ImageSignSecret: "secret", AlternativeImageProxy: "https://img.autorus.ru"},
+	}
+	for _, avito := range invalid {
+		if _, _, err := newImageURLProviders(&avito, "0005"); err == nil {
+			t.Errorf("newImageURLProviders(%+v) error = nil, want error", avito)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package imagename кодирует имена файлов изображений в ссылках фида и восстанавливает исходные имена.
+//
+// Кодирование нужно, чтобы Авито не распознавало одинаковые фотографии разных клиентов по ссылкам.
+// Версия кодирования отмечается в имени файла, поэтому прокси изображений декодирует ссылки
+// всех версий, выгруженных ранее.
+package imagename
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultVersion содержит версию кодирования имён изображений по умолчанию (encode4chars).
+const DefaultVersion = 1
+
+// Codec кодирует имена изображений. Кодирование должно быть детерминированным: одно и то же имя
+// с тем же seed всегда даёт одну и ту же ссылку, иначе Авито заново загружает фотографии.
+type Codec interface {
+	// Encode кодирует имя файла name (с расширением) с ключом клиента seed.
+	Encode(name, seed string) string
+	// Decode восстанавливает имя файла, закодированное Encode.
+	Decode(name, seed string) (string, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[int]Codec{
+		1: codecV1{},
+		2: codecV2{},
+	}
+)
+
+// Register регистрирует кодирование имён изображений версии version.
+// Зарегистрированные версии не должны меняться: по ним декодируются уже выгруженные ссылки.
+func Register(version int, codec Codec) {
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[version] = codec
+}
+
+func getCodec(version int) (Codec, bool) {
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[version]
+
+	return codec, ok
+}
+
+// versionSuffix отмечает версию кодирования в имени файла перед постфиксом: "{имя}_v2{pfx}.jpg".
+// Имена версии 1 отметки не имеют, чтобы ранее выгруженные ссылки не изменились.
+var versionSuffix = regexp.MustCompile(`_v(\d+)$`)
+
+// Encoder кодирует имена изображений клиента выбранной версией.
+type Encoder struct {
+	version int
+	codec   Codec
+	seed    string
+	pfx     string
+}
+
+// NewEncoder возвращает кодирование версии version (0 - DefaultVersion) с ключом клиента seed
+// и постфиксом выгрузки pfx. Версии выше 1 требуют seed.
+func NewEncoder(version int, seed, pfx string) (Encoder, error) {
+
+	if version == 0 {
+		version = DefaultVersion
+	}
+
+	codec, ok := getCodec(version)
+	if !ok {
+		return Encoder{}, errors.Errorf("неизвестная версия кодирования имён изображений %d", version)
+	}
+	if version > 1 && seed == "" {
+		return Encoder{}, errors.Errorf("для кодирования имён изображений версии %d не задан ключ", version)
+	}
+
+	return Encoder{version: version, codec: codec, seed: seed, pfx: pfx}, nil
+}
+
+// Encode возвращает закодированное имя файла с отметкой версии и постфиксом.
+func (e Encoder) Encode(name string) string {
+
+	pfx := e.pfx
+	if e.version > 1 {
+		pfx = "_v" + strconv.Itoa(e.version) + pfx
+	}
+
+	return addPostfix(e.codec.Encode(name, e.seed), pfx)
+}
+
+// Decode восстанавливает исходное имя изображения из имени файла в ссылке.
+// pfx - постфикс выгрузки ("0005"), seed - ключ клиента (для версии 1 не используется).
+// Версия кодирования определяется по имени, поэтому ссылки прежних версий продолжают работать.
+func Decode(name, pfx, seed string) (string, error) {
+
+	stem, ext := name, ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		stem, ext = name[:i], name[i:]
+	}
+
+	if !strings.HasSuffix(stem, pfx) {
+		return "", errors.Errorf("имя изображения %q не содержит постфикс %q", name, pfx)
+	}
+	stem = strings.TrimSuffix(stem, pfx)
+
+	version := 1
+	if m := versionSuffix.FindStringSubmatch(stem); m != nil {
+		version, _ = strconv.Atoi(m[1])
+		stem = strings.TrimSuffix(stem, m[0])
+	}
+
+	codec, ok := getCodec(version)
+	if !ok {
+		return "", errors.Errorf("неизвестная версия кодирования %d имени изображения %q", version, name)
+	}
+
+	return codec.Decode(stem+ext, seed)
+}
+
+// addPostfix добавляет постфикс pfx к имени файла name перед расширением.
+func addPostfix(name, pfx string) string {
+
+	ss := strings.Split(name, ".")
+	l := len(ss)
+
+	if l == 0 {
+		return pfx
+	}
+
+	if l == 1 {
+		return name + pfx
+	}
+
+	ss[l-2] = ss[l-2] + pfx
+
+	return strings.Join(ss, ".")
+}
+
+// codecV1 - исходное кодирование encode4chars, одинаковое для всех клиентов.
+type codecV1 struct{}
+
+func (codecV1) Encode(name, _ string) string {
+	return encode4chars(name)
+}
+
+func (codecV1) Decode(name, _ string) (string, error) {
+	return decode4chars(name)
+}
+
+// decode4chars удаляет символы, вставленные encode4chars на позиции 7, 14, 21 и 28,
+// и проверяет, что они совпадают со сдвинутыми символами исходного имени.
+func decode4chars(s string) (string, error) {
+
+	switch {
+	case len(s) < 34:
+		return s, nil
+	case len(s) < 38:
+		return "", errors.Errorf("имя изображения %q не закодировано encode4chars", s)
+	}
+
+	d := s[0:6] + s[7:13] + s[14:20] + s[21:27] + s[28:]
+	if encode4chars(d) != s {
+		return "", errors.Errorf("имя изображения %q не закодировано encode4chars", s)
+	}
+
+	return d, nil
+}
+
+// encMap - константный массив для функций encode4chars и decode4chars.
+var encMap = map[string]string{
+	"0": "7", "1": "8", "2": "9", "3": "a", "4": "b", "5": "c", "6": "d", "7": "e",
+	"8": "f", "9": "0", "a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6",
+}
+
+// encode4chars кодирует имя картинки по ТЗ от аналитики (версия 1 кодирования имён).
+//
+// ТЗ в виде комментариев в telegram-чате от PVA (заявки нет).
+// =======
+// Алгоритм модификации имени картинки
+//
+// Нормальное имя: 05375f5bf51cd0207d02e5655f14e89cb.jpeg
+// 34 символа в имени без точки и расширения
+//
+// Модифицированное имя: 05375f_5bf51c_d0207d_02e565_5f14e89cb.jpeg
+// 38 символов в имени без точки и расширения
+// Вместо символа "_" будет рандомный символ из набора 0-9a-f
+// Позиции в которые ВСТАВЛЯЮТСЯ символы: 7,14,21,28
+//
+// Вместо символа "_" будет сдвинутый на +7 символ из позиций 24,32,6,12
+// Позиции в которые ВСТАВЛЯЮТСЯ символы: 7,14,21,28
+// Сдвиг +7, это значит 0=7, 1=8, 2=9, 3=a, 4=b ... f=6
+//
+// Т.е. берутся символы из позиций 24,32,6,12
+// сдвигаются и вставляются на позиции 7,14,21,28
+func encode4chars(s string) string {
+
+	if len(s) < 34 {
+		return s
+	}
+
+	return s[0:6] + encMap[s[24:25]] +
+		s[6:12] + encMap[s[32:33]] +
+		s[12:18] + encMap[s[6:7]] +
+		s[18:24] + encMap[s[12:13]] +
+		s[24:]
+}
+
+// codecV2 сдвигает каждый шестнадцатеричный символ имени на значение
+// ключевого потока HMAC-SHA256(seed), поэтому кодирование у каждого клиента своё.
+// Остальные символы и расширение не меняются.
+type codecV2 struct{}
+
+func (codecV2) Encode(name, seed string) string {
+	return shiftName(name, seed, 1)
+}
+
+func (codecV2) Decode(name, seed string) (string, error) {
+	return shiftName(name, seed, -1), nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+func shiftName(name, seed string, sign int) string {
+
+	stem, ext := name, ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		stem, ext = name[:i], name[i:]
+	}
+
+	key := keyStream(seed, len(stem))
+	b := []byte(stem)
+	for i, c := range b {
+		v := strings.IndexByte(hexDigits, c)
+		if v < 0 {
+			continue
+		}
+		b[i] = hexDigits[(v+sign*int(key[i]%16)+16)%16]
+	}
+
+	return string(b) + ext
+}
+
+// keyStream возвращает n байт ключевого потока для seed.
+func keyStream(seed string, n int) []byte {
+
+	var (
+		stream  []byte
+		counter [8]byte
+	)
+	for i := uint64(0); len(stream) < n; i++ {
+		binary.BigEndian.PutUint64(counter[:], i)
+		mac := hmac.New(sha256.New, []byte(seed))
+		mac.Write(counter[:])
+		stream = mac.Sum(stream)
+	}
+
+	return stream[:n]
+}
@@ -0,0 +1,108 @@
+package imagename
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+
+	const name = "1149f97a082eb731bab1e4d0bb281be3e8.jpg"
+
+	tests := []struct {
+		name    string
+		version int
+		seed    string
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Version 1 by default",
+			image: name,
+			want:  "1149f927a082e5b731baeb1e4d02bb281be3e80005.jpg",
+		},
+		{
+			name:  "Version 1 short name",
+			image: "photo.jpg",
+			want:  "photo0005.jpg",
+		},
+		{
+			name:    "Version 2 with seed",
+			version: 2,
+			seed:    "client-1",
+			image:   name,
+		},
+		{
+			name:    "Version 2 without seed",
+			version: 2,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown version",
+			version: 99,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewEncoder(tt.version, tt.seed, "0005")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEncoder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := e.Encode(tt.image)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if got != e.Encode(tt.image) {
+				t.Errorf("Encode() is not stable")
+			}
+
+			decoded, err := Decode(got, "0005", tt.seed)
+			if err != nil || decoded != tt.image {
+				t.Errorf("Decode(%q) = %q, %v, want %q", got, decoded, err, tt.image)
+			}
+		})
+	}
+}
+
+func TestCodecV2Seeds(t *testing.T) {
+
+	const name = "1149f97a082eb731bab1e4d0bb281be3e8.jpg"
+
+	first, _ := NewEncoder(2, "client-1", "0005")
+	second, _ := NewEncoder(2, "client-2", "0005")
+
+	a, b := first.Encode(name), second.Encode(name)
+	if a == b {
+		t.Errorf("Encode() = %q for different seeds", a)
+	}
+	if !strings.HasSuffix(a, "_v20005.jpg") {
+		t.Errorf("Encode() = %q, want version suffix", a)
+	}
+
+	if decoded, err := Decode(a, "0005", "client-2"); err == nil && decoded == name {
+		t.Errorf("Decode() with other seed = %q", decoded)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	tests := []string{
+		"1149f927a082e5b731baeb1e4d02bb281be3e8.jpg",     // нет постфикса
+		"1149f9x7a082e5b731baeb1e4d02bb281be3e80005.jpg", // вставленный символ не совпадает
+		"1149f97a082eb731bab1e4d0bb281be3e80005.jpg",     // не закодировано
+		"1149f97a082eb731bab1e4d0bb281be3e8_v990005.jpg", // неизвестная версия
+	}
+
+	for _, name := range tests {
+		if got, err := Decode(name, "0005", "seed"); err == nil {
+			t.Errorf("Decode(%q) = %q, want error", name, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// avitoMaxImages содержит максимальное количество изображений в объявлении Авито.
+const avitoMaxImages = 10
+
+// Способы использования фотографий б/у товара.
+const (
+	usedImagesReplace = "replace" // только фотографии б/у товара
+	usedImagesPrepend = "prepend" // фотографии б/у товара, затем фотографии каталога
+	usedImagesAppend  = "append"  // фотографии каталога, затем фотографии б/у товара
+)
+
+// imagePolicyParams содержит настройки выбора изображений клиента.
+type imagePolicyParams struct {
+	MaxCount        int     `json:"maxCount"`        // 0 - avitoMaxImages
+	ProxyMaxCount   int     `json:"proxyMaxCount"`   // при AlternativeImageProxy, 0 - одно изображение
+	UsedImages      string  `json:"usedImages"`      // usedImagesReplace (по умолчанию), usedImagesPrepend, usedImagesAppend
+	MainFirst       bool    `json:"mainFirst"`       // изображение из свойства main_image первым
+	PreferredAspect float64 `json:"preferredAspect"` // ширина/высота; изображения из image_sizes ближе к нему выводятся раньше
+}
+
+// imagePolicy определяет, какие изображения и в каком порядке попадают в объявление.
+// Используется обоими фидами.
+type imagePolicy struct {
+	maxCount        int
+	usedImages      string
+	mainFirst       bool
+	preferredAspect float64
+}
+
+// newImagePolicy создаёт политику выбора изображений по параметрам клиента.
+func newImagePolicy(avito *avitoParams) imagePolicy {
+
+	var params imagePolicyParams
+	if avito.ImagePolicy != nil {
+		params = *avito.ImagePolicy
+	}
+
+	p := imagePolicy{
+		maxCount:        avitoMaxImages,
+		usedImages:      usedImagesReplace,
+		mainFirst:       params.MainFirst,
+		preferredAspect: params.PreferredAspect,
+	}
+
+	if params.MaxCount > 0 && params.MaxCount < avitoMaxImages {
+		p.maxCount = params.MaxCount
+	}
+
+	if avito.AlternativeImageProxy != "" {
+		proxyMax := 1
+		if params.ProxyMaxCount > 0 {
+			proxyMax = params.ProxyMaxCount
+		}
+		p.maxCount = min(p.maxCount, proxyMax)
+	}
+
+	switch params.UsedImages {
+	case usedImagesPrepend, usedImagesAppend:
+		p.usedImages = params.UsedImages
+	}
+
+	return p
+}
+
+// replacesCatalog сообщает, заменяют ли фотографии б/у товара фотографии каталога.
+func (ip imagePolicy) replacesCatalog(pos position) bool {
+	return pos.Condition != 0 && ip.usedImages == usedImagesReplace
+}
+
+// selectImages возвращает имена изображений позиции pos в порядке вывода:
+// фотографии каталога из свойства images упорядочиваются по свойствам main_image и image_sizes,
+// объединяются с фотографиями б/у товара, повторяющиеся имена удаляются.
+// Количество изображений не ограничивается: ограничение применяет limit
+// после отбора недоступных изображений.
+func (ip imagePolicy) selectImages(pos position, p propReader) []string {
+
+	catalog, _ := p.Strings("images")
+	catalog = ip.orderCatalog(catalog, p)
+
+	var names []string
+	switch {
+	case pos.Condition == 0:
+		names = catalog
+	case ip.usedImages == usedImagesPrepend:
+		names = append(append(names, pos.UsedImages...), catalog...)
+	case ip.usedImages == usedImagesAppend:
+		names = append(append(names, catalog...), pos.UsedImages...)
+	default:
+		names = pos.UsedImages
+	}
+
+	out := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := imageNameKey(name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		out = append(out, name)
+	}
+
+	return out
+}
+
+// limit ограничивает количество изображений предложения.
+func (ip imagePolicy) limit(images []xmlImage) []xmlImage {
+
+	if len(images) > ip.maxCount {
+		return images[:ip.maxCount]
+	}
+
+	return images
+}
+
+// orderCatalog упорядочивает фотографии каталога.
+// Свойство image_sizes содержит размеры "ШИРИНАxВЫСОТА" в порядке свойства images.
+func (ip imagePolicy) orderCatalog(images []string, p propReader) []string {
+
+	if len(images) < 2 || (!ip.mainFirst && ip.preferredAspect <= 0) {
+		return images
+	}
+
+	type candidate struct {
+		name     string
+		main     bool
+		distance float64
+	}
+
+	main, _ := p.String("main_image")
+	sizes, _ := p.Strings("image_sizes")
+
+	candidates := make([]candidate, 0, len(images))
+	for i, name := range images {
+		c := candidate{name: name, distance: math.Inf(1)}
+		if ip.mainFirst && main != "" {
+			c.main = imageNameKey(name) == imageNameKey(main)
+		}
+		if ip.preferredAspect > 0 && i < len(sizes) {
+			if aspect, ok := parseImageAspect(sizes[i]); ok {
+				c.distance = math.Abs(aspect - ip.preferredAspect)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].main != candidates[j].main {
+			return candidates[i].main
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	out := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		out = append(out, c.name)
+	}
+
+	return out
+}
+
+// imageNameKey возвращает имя файла изображения без пути и параметров для сравнения.
+func imageNameKey(name string) string {
+
+	name = strings.TrimSpace(name)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" {
+		return ""
+	}
+
+	return strings.ToLower(path.Base(name))
+}
+
+// parseImageAspect возвращает отношение ширины к высоте из размера "ШИРИНАxВЫСОТА".
+func parseImageAspect(size string) (float64, bool) {
+
+	size = strings.ToLower(strings.TrimSpace(size))
+	w, h, ok := strings.Cut(strings.ReplaceAll(size, "х", "x"), "x")
+	if !ok {
+		return 0, false
+	}
+
+	width, errW := strconv.ParseFloat(strings.TrimSpace(w), 64)
+	height, errH := strconv.ParseFloat(strings.TrimSpace(h), 64)
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, false
+	}
+
+	return width / height, true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImagePolicySelectImages(t *testing.T) {
+
+	catalog := Properties{
+		"images":      []any{"a.jpg", "b.jpg", "c.jpg", "A.JPG", "d.jpg"},
+		"main_image":  "c.jpg",
+		"image_sizes": []any{"400x800", "800x600", "1000x1000", "400x800", "bad"},
+	}
+	used := position{Condition: 30, UsedImages: []string{"u1.jpg", "b.jpg", "u2.jpg"}}
+
+	twelve := make([]any, 0, 12)
+	for _, name := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"} {
+		twelve = append(twelve, name+".jpg")
+	}
+
+	tests := []struct {
+		name  string
+		avito avitoParams
+		pos   position
+		props Properties
+		want  []string
+	}{
+		{
+			name:  "Default dedup",
+			props: catalog,
+			want:  []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg"},
+		},
+		{
+			name:  "Avito limit",
+			props: Properties{"images": twelve},
+			want:  []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg", "6.jpg", "7.jpg", "8.jpg", "9.jpg", "10.jpg"},
+		},
+		{
+			name:  "Client limit",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{MaxCount: 2}},
+			props: catalog,
+			want:  []string{"a.jpg", "b.jpg"},
+		},
+		{
+			name:  "Proxy default limit",
+			avito: avitoParams{AlternativeImageProxy: "https://proxy"},
+			props: catalog,
+			want:  []string{"a.jpg"},
+		},
+		{
+			name:  "Proxy client limit",
+			avito: avitoParams{AlternativeImageProxy: "https://proxy", ImagePolicy: &imagePolicyParams{ProxyMaxCount: 3}},
+			props: catalog,
+			want:  []string{"a.jpg", "b.jpg", "c.jpg"},
+		},
+		{
+			name:  "Main first",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{MainFirst: true}},
+			props: catalog,
+			want:  []string{"c.jpg", "a.jpg", "b.jpg", "d.jpg"},
+		},
+		{
+			name:  "Preferred aspect",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{PreferredAspect: 4.0 / 3}},
+			props: catalog,
+			want:  []string{"b.jpg", "c.jpg", "a.jpg", "d.jpg"},
+		},
+		{
+			name:  "Main first with aspect",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{MainFirst: true, PreferredAspect: 4.0 / 3}},
+			props: catalog,
+			want:  []string{"c.jpg", "b.jpg", "a.jpg", "d.jpg"},
+		},
+		{
+			name:  "Used replace",
+			pos:   used,
+			props: catalog,
+			want:  []string{"u1.jpg", "b.jpg", "u2.jpg"},
+		},
+		{
+			name:  "Used prepend",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{UsedImages: usedImagesPrepend}},
+			pos:   used,
+			props: catalog,
+			want:  []string{"u1.jpg", "b.jpg", "u2.jpg", "a.jpg", "c.jpg", "d.jpg"},
+		},
+		{
+			name:  "Used append",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{UsedImages: usedImagesAppend}},
+			pos:   used,
+			props: catalog,
+			want:  []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg", "u1.jpg", "u2.jpg"},
+		},
+		{
+			name:  "Unknown used mode",
+			avito: avitoParams{ImagePolicy: &imagePolicyParams{UsedImages: "mix"}},
+			pos:   used,
+			props: catalog,
+			want:  []string{"u1.jpg", "b.jpg", "u2.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newImagePolicy(&tt.avito)
+			var images []xmlImage
+			for _, name := range policy.selectImages(tt.pos, newPropReader(tt.props, "BRAND|NUM", nil)) {
+				images = append(images, xmlImage{URL: name})
+			}
+
+			var got []string
+			for _, img := range policy.limit(images) {
+				got = append(got, img.URL)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("selectImages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageNameKey(t *testing.T) {
+
+	tests := map[string]string{
+		"a.jpg":                          "a.jpg",
+		" https://host/x/A.JPG?1 ":       "a.jpg",
+		"https://host/images/b.png#frag": "b.png",
+		"":                               "",
+	}
+
+	for in, want := range tests {
+		if got := imageNameKey(in); got != want {
+			t.Errorf("imageNameKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package imagesign подписывает ссылки на изображения альтернативного прокси и проверяет подпись.
+//
+// Подпись HMAC-SHA256 вычисляется по пути ссылки, идентификатору и версии ключа клиента
+// и сроку действия. Срок действия округляется до конца периода (см. Expiry), поэтому
+// в пределах периода ссылка не меняется между формированиями фида. Прокси выбирает секрет
+// по идентификатору и версии ключа из ссылки: идентификатор уникален для каждого клиента.
+// Чтобы отозвать ссылки досрочно, оператор выпускает ключ новой версии и убирает старую из ключей прокси.
+package imagesign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Параметры запроса подписанной ссылки на изображение.
+const (
+	KeyIDParam     = "kid"
+	VersionParam   = "kv"
+	ExpiresParam   = "exp"
+	SignatureParam = "sig"
+)
+
+// Ошибки проверки подписанной ссылки на изображение.
+var (
+	ErrUnsigned   = errors.New("ссылка на изображение не подписана")
+	ErrUnknownKey = errors.New("неизвестный ключ подписи ссылки на изображение")
+	ErrSignature  = errors.New("неверная подпись ссылки на изображение")
+	ErrExpired    = errors.New("срок действия ссылки на изображение истёк")
+)
+
+// KeyID идентифицирует ключ подписи: клиент и версия ключа.
+type KeyID struct {
+	Client  string
+	Version int
+}
+
+// Key содержит ключ подписи клиента.
+type Key struct {
+	KeyID
+	Secret string
+}
+
+// Expiry возвращает срок действия ссылки, подписанной в момент now: конец периода period,
+// следующего за текущим. Ссылка действует не меньше period и не меняется до конца текущего периода.
+func Expiry(now time.Time, period time.Duration) time.Time {
+
+	seconds := int64(period / time.Second)
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	return time.Unix((now.Unix()/seconds+2)*seconds, 0)
+}
+
+// signature возвращает подпись пути path со сроком действия expires ключом key.
+// Подписывается только путь: остальные параметры запроса (UpdatePhoto, ImageInc) не входят в подпись.
+func signature(key Key, path string, expires int64) string {
+
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	mac.Write([]byte(path + "\n" + key.Client + "\n" + strconv.Itoa(key.Version) + "\n" + strconv.FormatInt(expires, 10)))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign добавляет к ссылке u идентификатор и версию ключа, срок действия expires и подпись ключом key.
+func Sign(u *url.URL, key Key, expires time.Time) {
+
+	q := url.Values{}
+	q.Set(KeyIDParam, key.Client)
+	q.Set(VersionParam, strconv.Itoa(key.Version))
+	q.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	token := q.Encode() + "&" + SignatureParam + "=" + signature(key, u.EscapedPath(), expires.Unix())
+
+	if u.RawQuery == "" {
+		u.RawQuery = token
+		return
+	}
+	u.RawQuery += "&" + token
+}
+
+// Verify проверяет подпись ссылки на изображение rawURL в момент now.
+// keys содержит действующие ключи прокси: ключ клиента -> секрет.
+func Verify(rawURL string, keys map[KeyID]string, now time.Time) error {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "ссылка на изображение")
+	}
+
+	q := u.Query()
+	sig := q.Get(SignatureParam)
+	if sig == "" || q.Get(KeyIDParam) == "" || q.Get(VersionParam) == "" || q.Get(ExpiresParam) == "" {
+		return ErrUnsigned
+	}
+
+	version, err := strconv.Atoi(q.Get(VersionParam))
+	if err != nil {
+		return ErrSignature
+	}
+	expires, err := strconv.ParseInt(q.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+
+	id := KeyID{Client: q.Get(KeyIDParam), Version: version}
+	secret, ok := keys[id]
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	want := signature(Key{KeyID: id, Secret: secret}, u.EscapedPath(), expires)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return ErrSignature
+	}
+
+	if now.Unix() >= expires {
+		return ErrExpired
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package imagesign
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+
+	period := 30 * 24 * time.Hour
+	start := time.Unix(0, 0).Add(100 * period)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "Period start", now: start, want: start.Add(2 * period)},
+		{name: "Period end", now: start.Add(period - time.Second), want: start.Add(2 * period)},
+		{name: "Next period", now: start.Add(period), want: start.Add(3 * period)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expiry(tt.now, period); !got.Equal(tt.want) {
+				t.Errorf("Expiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+
+	key := Key{KeyID: KeyID{Client: "client-a", Version: 2}, Secret: "secret"}
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Hour)
+
+	u, _ := url.Parse("https://img.autorus.ru/images/mann%20filter/photo.jpg?3")
+	Sign(u, key, expires)
+	signed := u.String()
+
+	if !strings.HasPrefix(signed, "https://img.autorus.ru/images/mann%20filter/photo.jpg?3&exp=1700003600&kid=client-a&kv=2&sig=") {
+		t.Fatalf("Sign() = %q, want signed URL", signed)
+	}
+
+	again, _ := url.Parse("https://img.autorus.ru/images/mann%20filter/photo.jpg?3")
+	Sign(again, key, expires)
+	if again.String() != signed {
+		t.Errorf("Sign() = %q, want stable %q", again.String(), signed)
+	}
+
+	keys := map[KeyID]string{
+		{Client: "client-a", Version: 1}: "old",
+		{Client: "client-a", Version: 2}: "secret",
+		{Client: "client-b", Version: 2}: "other",
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		keys map[KeyID]string
+		now  time.Time
+		want error
+	}{
+		{name: "Valid with image inc", url: signed + "&1", keys: keys, now: now},
+		{name: "Expired", url: signed, keys: keys, now: expires, want: ErrExpired},
+		{name: "Revoked key", url: signed, keys: map[KeyID]string{{Client: "client-a", Version: 3}: "next"}, now: now, want: ErrUnknownKey},
+		{name: "Other client secret", url: strings.Replace(signed, "kid=client-a", "kid=client-b", 1), keys: keys, now: now, want: ErrSignature},
+		{name: "Other path", url: strings.Replace(signed, "photo.jpg", "other.jpg", 1), keys: keys, now: now, want: ErrSignature},
+		{name: "Forged version", url: strings.Replace(signed, "kv=2", "kv=1", 1), keys: map[KeyID]string{{Client: "client-a", Version: 1}: "secret"}, now: now, want: ErrSignature},
+		{name: "Forged expiry", url: strings.Replace(signed, "exp=1700003600", "exp=1800000000", 1), keys: keys, now: now, want: ErrSignature},
+		{name: "Unsigned", url: "https://img.autorus.ru/images/photo.jpg", keys: keys, now: now, want: ErrUnsigned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.url, tt.keys, tt.now); err != tt.want {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
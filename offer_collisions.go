@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
+)
+
+// Правила выбора предложения при совпадении идентификаторов.
+const (
+	offerIDKeepFirst    = "keep_first"    // остаётся первое предложение (по умолчанию)
+	offerIDKeepCheapest = "keep_cheapest" // остаётся предложение с меньшей ценой
+	offerIDKeepInStock  = "keep_in_stock" // остаётся предложение в наличии
+	offerIDSuffix       = "suffix"        // следующие предложения получают суффикс по маршруту и коду склада: "-2", "-2-WH1"
+)
+
+// offerCandidate описывает позицию, претендующую на идентификатор предложения.
+type offerCandidate struct {
+	key   string // бренд|артикул|маршрут|код склада, см. offerKey
+	route int
+	code  string
+	price float64
+	stock int
+}
+
+func newOfferCandidate(pos position) offerCandidate {
+	return offerCandidate{
+		key:   offerKey(pos),
+		route: pos.RouteID,
+		code:  strings.TrimSpace(pos.Code),
+		price: pos.PriceSale,
+		stock: pos.Availability,
+	}
+}
+
+// suffix возвращает суффикс идентификатора позиции при правиле offerIDSuffix.
+// Суффикс определяется маршрутом и кодом склада позиции, а не порядком позиций в прайсе.
+func (c offerCandidate) suffix() string {
+
+	suffix := "-" + strconv.Itoa(c.route)
+	if c.code != "" {
+		suffix += "-" + c.code
+	}
+
+	return suffix
+}
+
+// offerIDCollisions отслеживает идентификаторы предложений задачи во всех пакетах позиций,
+// чтобы совпавшие идентификаторы разных позиций не заменяли друг друга незаметно.
+type offerIDCollisions struct {
+	policy string
+	seen   map[string]offerCandidate
+	store  *offerids.Store
+	report *feedReport
+}
+
+// newOfferIDCollisions возвращает отслеживание совпадений с правилом policy (см. offerIDKeepFirst).
+// Идентификаторы с суффиксом закрепляются в хранилище store, чтобы в следующих выгрузках
+// позиции получали их сразу, независимо от порядка позиций.
+func newOfferIDCollisions(policy string, store *offerids.Store, report *feedReport) (*offerIDCollisions, error) {
+
+	switch policy {
+	case "":
+		policy = offerIDKeepFirst
+	case offerIDKeepFirst, offerIDKeepCheapest, offerIDKeepInStock, offerIDSuffix:
+	default:
+		return nil, errors.Errorf("неизвестное правило совпадения идентификаторов %q", policy)
+	}
+
+	return &offerIDCollisions{
+		policy: policy,
+		seen:   make(map[string]offerCandidate),
+		store:  store,
+		report: report,
+	}, nil
+}
+
+// buffered сообщает, что правило может заменить уже принятое предложение,
+// поэтому предложения задачи выгружаются после обработки всех пакетов.
+func (c *offerIDCollisions) buffered() bool {
+	return c.policy == offerIDKeepCheapest || c.policy == offerIDKeepInStock
+}
+
+// add регистрирует предложение с идентификатором id позиции cand.
+// Возвращает идентификатор, под которым предложение выгружается, и false,
+// если предложение не попадает в фид. Для правил buffered принятое предложение
+// заменяет ранее принятое с тем же идентификатором.
+func (c *offerIDCollisions) add(id string, cand offerCandidate) (string, bool) {
+
+	prev, ok := c.seen[id]
+	if !ok {
+		c.seen[id] = cand
+		return id, true
+	}
+
+	// Повтор той же позиции не является совпадением идентификаторов разных позиций.
+	if prev.key != cand.key {
+		c.report.addOfferIDCollision(id, prev.key, cand.key)
+	}
+
+	switch c.policy {
+	case offerIDKeepCheapest:
+		if cand.price < prev.price {
+			c.seen[id] = cand
+			return id, true
+		}
+	case offerIDKeepInStock:
+		if prev.stock <= 0 && cand.stock > 0 {
+			c.seen[id] = cand
+			return id, true
+		}
+	case offerIDSuffix:
+		if prev.key == cand.key {
+			return id, false
+		}
+		suffixed := id + cand.suffix()
+		for n := 2; ; n++ {
+			if _, used := c.seen[suffixed]; !used {
+				break
+			}
+			// Маршрут и код склада совпадают у позиций с разными брендами или артикулами.
+			suffixed = id + cand.suffix() + "-" + strconv.Itoa(n)
+		}
+		c.seen[suffixed] = cand
+		c.store.Assign(cand.key, suffixed)
+		return suffixed, true
+	}
+
+	return id, false
+}
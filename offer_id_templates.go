@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// offerIDFields содержит значения подстановок шаблона идентификатора предложения.
+var offerIDFields = map[string]func(pos position) string{
+	"code":   func(pos position) string { return strings.TrimSpace(pos.Code) },
+	"brand":  func(pos position) string { return offerIDBrand(pos.Brand) },
+	"number": func(pos position) string { return strings.TrimSpace(pos.Number) },
+	"route": func(pos position) string {
+		if pos.RouteID == 0 {
+			return ""
+		}
+		return strconv.Itoa(pos.RouteID)
+	},
+	// md5 и md5_noroute совпадают с идентификаторами режимов AvitoOfferID 0 и 1.
+	"md5":         func(pos position) string { return offerIDHash(pos.Number + pos.Brand + strconv.Itoa(pos.RouteID)) },
+	"md5_noroute": func(pos position) string { return offerIDHash(pos.Number + pos.Brand) },
+}
+
+// offerIDFieldNames содержит имена полей offerIDFields, длинные имена проверяются первыми.
+var offerIDFieldNames = []string{"md5_noroute", "number", "brand", "route", "code", "md5"}
+
+// offerIDTemplate формирует идентификатор предложения по шаблону клиента, например
+// "{code|brand-number}-{route}".
+//
+// Подстановка в фигурных скобках содержит варианты через "|", используется первый вариант,
+// все поля которого заполнены. Вариант состоит из полей offerIDFields и разделителей между ними.
+// Текст вне фигурных скобок выводится без изменений.
+type offerIDTemplate struct {
+	nodes []offerIDNode
+}
+
+// offerIDNode содержит текст шаблона или варианты подстановки.
+type offerIDNode struct {
+	text         string
+	alternatives [][]offerIDToken
+}
+
+// offerIDToken содержит имя поля или разделитель варианта подстановки.
+type offerIDToken struct {
+	field string
+	text  string
+}
+
+// newOfferIDTemplate разбирает шаблон идентификатора text. Пустой шаблон означает режим AvitoOfferID.
+func newOfferIDTemplate(text string) (*offerIDTemplate, error) {
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	t := &offerIDTemplate{}
+	hasField := false
+	for rest := text; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			t.nodes = append(t.nodes, offerIDNode{text: rest})
+			break
+		}
+		if start > 0 {
+			t.nodes = append(t.nodes, offerIDNode{text: rest[:start]})
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, errors.Errorf("шаблон идентификатора %q: не закрыта подстановка", text)
+		}
+
+		node := offerIDNode{}
+		for _, alt := range strings.Split(rest[start+1:start+end], "|") {
+			tokens, err := parseOfferIDAlternative(strings.TrimSpace(alt))
+			if err != nil {
+				return nil, errors.Wrapf(err, "шаблон идентификатора %q", text)
+			}
+			hasField = true
+			node.alternatives = append(node.alternatives, tokens)
+		}
+		t.nodes = append(t.nodes, node)
+		rest = rest[start+end+1:]
+	}
+
+	if !hasField {
+		return nil, errors.Errorf("шаблон идентификатора %q не содержит полей", text)
+	}
+
+	return t, nil
+}
+
+// parseOfferIDAlternative разбивает вариант подстановки на поля и разделители между ними,
+// например "brand_number" - на brand, "_" и number.
+func parseOfferIDAlternative(alt string) ([]offerIDToken, error) {
+
+	var tokens []offerIDToken
+	for rest := alt; rest != ""; {
+		field := ""
+		for _, name := range offerIDFieldNames {
+			if strings.HasPrefix(rest, name) {
+				field = name
+				break
+			}
+		}
+		if field != "" {
+			tokens = append(tokens, offerIDToken{field: field})
+			rest = rest[len(field):]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		if unicode.IsLetter(r) {
+			return nil, errors.Errorf("неизвестное поле в подстановке %q", alt)
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].field == "" {
+			tokens[n-1].text += rest[:size]
+		} else {
+			tokens = append(tokens, offerIDToken{text: rest[:size]})
+		}
+		rest = rest[size:]
+	}
+
+	for _, token := range tokens {
+		if token.field != "" {
+			return tokens, nil
+		}
+	}
+
+	return nil, errors.Errorf("подстановка %q не содержит полей", alt)
+}
+
+// build возвращает идентификатор предложения позиции pos.
+// Пустая строка означает, что ни одна подстановка не заполнена.
+func (t *offerIDTemplate) build(pos position) string {
+
+	var (
+		sb     strings.Builder
+		filled bool
+	)
+	for _, node := range t.nodes {
+		if node.alternatives == nil {
+			sb.WriteString(node.text)
+			continue
+		}
+		if value := node.resolve(pos); value != "" {
+			sb.WriteString(value)
+			filled = true
+		}
+	}
+
+	if !filled {
+		return ""
+	}
+
+	return strings.Trim(sb.String(), "-_ ")
+}
+
+// resolve возвращает первый вариант подстановки, все поля которого заполнены.
+func (n offerIDNode) resolve(pos position) string {
+
+	for _, alt := range n.alternatives {
+		var sb strings.Builder
+		complete := true
+		for _, token := range alt {
+			if token.field == "" {
+				sb.WriteString(token.text)
+				continue
+			}
+			value := offerIDFields[token.field](pos)
+			if value == "" {
+				complete = false
+				break
+			}
+			sb.WriteString(value)
+		}
+		if complete {
+			return sb.String()
+		}
+	}
+
+	return ""
+}
+
+// offerIDBrand приводит бренд к виду, допустимому в идентификаторе предложения.
+func offerIDBrand(brand string) string {
+
+	b := strings.ReplaceAll(brand, "ё", "е")
+	b = strings.ReplaceAll(b, "&", "_")
+
+	return strings.ReplaceAll(b, " ", "")
+}
+
+// offerIDHash возвращает первые 20 символов md5 строки s.
+func offerIDHash(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))[0:20]
+}
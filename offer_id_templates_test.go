@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
+)
+
+func TestOfferIDTemplate(t *testing.T) {
+
+	pos := position{Brand: "Liqui Moly", Number: "3926", RouteID: 12}
+	withCode := position{Brand: "Liqui Moly", Number: "3926", RouteID: 12, Code: " WH-001 "}
+
+	tests := []struct {
+		name     string
+		template string
+		pos      position
+		want     string
+		wantErr  bool
+	}{
+		{name: "Code", template: "{code|brand-number}-{route}", pos: withCode, want: "WH-001-12"},
+		{name: "Brand and number without code", template: "{code|brand-number}-{route}", pos: pos, want: "LiquiMoly-3926-12"},
+		{name: "Empty route trimmed", template: "{code|brand-number}-{route}", pos: position{Brand: "Mobil", Number: "1"}, want: "Mobil-1"},
+		{name: "Underscore separator", template: "{brand_number}", pos: pos, want: "LiquiMoly_3926"},
+		{name: "Hash fields match modes", template: "{md5}", pos: pos, want: buildOfferID(pos, 0)},
+		{name: "Hash without route", template: "a{md5_noroute}", pos: pos, want: "a" + buildOfferID(pos, 1)},
+		{name: "Nothing filled", template: "{code}", pos: pos},
+		{name: "Unknown field", template: "{brand-sku}", wantErr: true},
+		{name: "Unclosed", template: "{brand", wantErr: true},
+		{name: "Empty alternative", template: "{code|}", wantErr: true},
+		{name: "No fields", template: "offer", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newOfferIDTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newOfferIDTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := tmpl.build(tt.pos); got != tt.want {
+				t.Errorf("build() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if tmpl, err := newOfferIDTemplate(" "); tmpl != nil || err != nil {
+		t.Errorf("newOfferIDTemplate() = %v, %v, want nil", tmpl, err)
+	}
+}
+
+func TestOfferIDCollisions(t *testing.T) {
+
+	first := position{Brand: "Bosch", Number: "F 026", PriceSale: 500, Availability: 0}
+	second := position{Brand: "BOSCH", Number: "F026", RouteID: 2, PriceSale: 300, Availability: 4}
+	third := position{Brand: "Bosch", Number: "F-026", RouteID: 2, Code: "WH1", PriceSale: 400, Availability: 2}
+
+	type result struct {
+		ID string
+		OK bool
+	}
+
+	tests := []struct {
+		policy string
+		want   []result
+	}{
+		{policy: "", want: []result{{"id", true}, {"id", false}, {"id", false}, {"id", false}}},
+		{policy: offerIDKeepCheapest, want: []result{{"id", true}, {"id", true}, {"id", false}, {"id", false}}},
+		{policy: offerIDKeepInStock, want: []result{{"id", true}, {"id", true}, {"id", false}, {"id", false}}},
+		{policy: offerIDSuffix, want: []result{{"id", true}, {"id-2", true}, {"id-2-WH1", true}, {"id", false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			report := newFeedReport()
+			c, err := newOfferIDCollisions(tt.policy, nil, report)
+			if err != nil {
+				t.Fatalf("newOfferIDCollisions() error = %v", err)
+			}
+
+			var got []result
+			for _, pos := range []position{first, second, third, first} {
+				id, ok := c.add("id", newOfferCandidate(pos))
+				got = append(got, result{id, ok})
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("add() mismatch (-want +got):\n%s", diff)
+			}
+
+			wantReport := []string{"BOSCH|F 026|0|", "BOSCH|F026|2|", "BOSCH|F-026|2|WH1"}
+			if diff := cmp.Diff(wantReport, report.offerIDCollisions["id"]); diff != "" {
+				t.Errorf("offerIDCollisions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := newOfferIDCollisions("keep_last", nil, nil); err == nil {
+		t.Error("newOfferIDCollisions() error = nil, want error")
+	}
+}
+
+func TestOfferIDSuffixStable(t *testing.T) {
+
+	store, err := offerids.Open(filepath.Join(t.TempDir(), "offer-ids.json"))
+	if err != nil {
+		t.Fatalf("offerids.Open() error = %v", err)
+	}
+
+	// Шаблон без маршрута даёт позициям разных маршрутов один идентификатор.
+	first := position{Brand: "Bosch", Number: "F026", RouteID: 1}
+	second := position{Brand: "Bosch", Number: "F026", RouteID: 2}
+	b := &offerBuilder{avito: &avitoParams{}, offerIDStore: store}
+	if b.offerIDTemplate, err = newOfferIDTemplate("{brand}-{number}"); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(positions ...position) map[int]string {
+		c, _ := newOfferIDCollisions(offerIDSuffix, store, nil)
+		ids := make(map[int]string)
+		for _, pos := range positions {
+			id, _ := c.add(b.offerID(pos), newOfferCandidate(pos))
+			ids[pos.RouteID] = id
+		}
+		return ids
+	}
+
+	want := map[int]string{1: "Bosch-F026", 2: "Bosch-F026-2"}
+	if diff := cmp.Diff(want, run(first, second)); diff != "" {
+		t.Errorf("first run mismatch (-want +got):\n%s", diff)
+	}
+	// Порядок позиций в следующей выгрузке не меняет идентификаторы.
+	if diff := cmp.Diff(want, run(second, first)); diff != "" {
+		t.Errorf("reordered run mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"gitlab.nodasoft.com/pricegen/avito/offerids"
+)
+
+// offerKey возвращает ключ позиции pos в хранилище идентификаторов и при отслеживании совпадений:
+// бренд, артикул, маршрут и код склада. При смене маршрута идентификатор переходит к новому ключу
+// (см. offerids.Store.Resolve).
+func offerKey(pos position) string {
+	return offerids.Key(pos.Brand, pos.Number, pos.RouteID, pos.Code)
+}
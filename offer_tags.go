@@ -0,0 +1,18 @@
+package main
+
+// AdTags содержит теги <Ad>, которые заполняются модулем сверх базового набора xmlOffer.
+// Структура встраивается в xmlOffer без имени поля: encoding/xml выводит её поля
+// как обычные дочерние теги объявления, а сами поля доступны как offer.WheelDiameter и т.д.
+type AdTags struct {
+	// Теги группы товаров bicycles.
+	WheelDiameter string `xml:"WheelDiameter,omitempty"`
+	FrameSize     string `xml:"FrameSize,omitempty"`
+	Speeds        int    `xml:"Speeds,omitempty"`
+
+	// Теги автомобиля-донора б/у запчасти.
+	Make string `xml:"Make,omitempty"`
+	Year string `xml:"Year,omitempty"`
+
+	// Дата начала публикации объявления, см. avitoParams.DateBegin.
+	DateBegin string `xml:"DateBegin,omitempty"`
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAdTagsMarshal(t *testing.T) {
+
+	type ad struct {
+		XMLName xml.Name `xml:"Ad"`
+		ID      string
+		AdTags
+	}
+
+	tests := []struct {
+		name string
+		tags AdTags
+		want string
+	}{
+		{
+			name: "Bicycle",
+			tags: AdTags{WheelDiameter: "27,5", FrameSize: "M", Speeds: 21},
+			want: "<Ad><ID>1</ID><WheelDiameter>27,5</WheelDiameter><FrameSize>M</FrameSize><Speeds>21</Speeds></Ad>",
+		},
+		{
+			name: "Donor vehicle",
+			tags: AdTags{Make: "Toyota", Year: "2015"},
+			want: "<Ad><ID>1</ID><Make>Toyota</Make><Year>2015</Year></Ad>",
+		},
+		{
+			name: "Deferred publication",
+			tags: AdTags{DateBegin: "2026-10-20"},
+			want: "<Ad><ID>1</ID><DateBegin>2026-10-20</DateBegin></Ad>",
+		},
+		{
+			name: "Empty tags are omitted",
+			want: "<Ad><ID>1</ID></Ad>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := xml.Marshal(ad{ID: "1", AdTags: tt.tags})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("xml.Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,495 @@
+// Package offerids хранит идентификаторы предложений, уже выгруженных на Авито.
+//
+// Идентификатор закрепляется за позицией прайса - брендом, артикулом, маршрутом и кодом склада
+// (см. Key) - и не меняется при смене режима AvitoOfferID или шаблона идентификатора,
+// поэтому Авито не создаёт для позиции новое объявление.
+//
+// При смене маршрута позиция получает идентификатор того же бренда, артикула и кода склада
+// с другим маршрутом, если такой идентификатор один и ещё не запрошен позицией в текущем
+// формировании фида (до Save). Если подходящих идентификаторов несколько, позиция получает новый.
+//
+// Хранилища прежнего формата содержат ключи "БРЕНД|АРТИКУЛ" без маршрута и кода склада.
+// Такой идентификатор переходит к первой позиции этого бренда и артикула, запросившей
+// идентификатор; позиции других маршрутов получают собственные идентификаторы.
+package offerids
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// keySep разделяет части ключа позиции.
+const keySep = "|"
+
+// Key возвращает ключ позиции: "БРЕНД|АРТИКУЛ|маршрут|КОД СКЛАДА".
+func Key(brand, number string, route int, code string) string {
+	return strings.Join([]string{legacyKey(brand, number), strconv.Itoa(route), strings.ToUpper(strings.TrimSpace(code))}, keySep)
+}
+
+// legacyKey возвращает ключ позиции в хранилищах прежнего формата: "БРЕНД|АРТИКУЛ".
+func legacyKey(brand, number string) string {
+	return strings.ToUpper(strings.TrimSpace(brand)) + keySep + strings.ToUpper(strings.TrimSpace(number))
+}
+
+// legacyOf возвращает ключ прежнего формата для ключа позиции key.
+func legacyOf(key string) (string, bool) {
+
+	parts := strings.Split(key, keySep)
+	if len(parts) != 4 {
+		return "", false
+	}
+
+	return parts[0] + keySep + parts[1], true
+}
+
+// itemOf возвращает ключ позиции key без маршрута: "БРЕНД|АРТИКУЛ|КОД СКЛАДА".
+func itemOf(key string) (string, bool) {
+
+	parts := strings.Split(key, keySep)
+	if len(parts) != 4 {
+		return "", false
+	}
+
+	return parts[0] + keySep + parts[1] + keySep + parts[3], true
+}
+
+// Store хранит соответствие ключей позиций и идентификаторов предложений в файле.
+// Методы безопасны для вызова на nil-указателе и из нескольких задач одновременно.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	ids      map[string]string          // ключ позиции -> идентификатор
+	owners   map[string]string          // идентификатор -> ключ позиции
+	items    map[string]map[string]bool // ключ без маршрута -> ключи позиций
+	resolved map[string]bool            // ключи позиций, запрошенные после последнего Save
+	modTime  time.Time                  // время изменения файла при последней загрузке или сохранении
+	dirty    bool
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// Open возвращает общее для процесса хранилище из файла path.
+// Если файл изменён после загрузки или сохранения хранилища, например командой offerids import,
+// хранилище загружается заново. Пустой path отключает хранилище. Повреждённый файл не заменяется
+// пустым хранилищем, иначе все объявления клиента получили бы новые идентификаторы.
+func Open(path string) (*Store, error) {
+
+	if path == "" {
+		return nil, nil
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	s, ok := stores[path]
+	if !ok {
+		s = newStore(path)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, errors.Wrap(err, "загрузка идентификаторов предложений")
+	}
+	stores[path] = s
+
+	return s, nil
+}
+
+func newStore(path string) *Store {
+	return &Store{
+		path:     path,
+		ids:      make(map[string]string),
+		owners:   make(map[string]string),
+		items:    make(map[string]map[string]bool),
+		resolved: make(map[string]bool),
+	}
+}
+
+// Resolve возвращает закреплённый за позицией key идентификатор или закрепляет за ней id.
+// Идентификатор той же позиции с другим маршрутом и идентификатор позиции из хранилища
+// прежнего формата переносятся на key. Без хранилища возвращается id.
+func (s *Store) Resolve(key, id string) string {
+
+	if s == nil || id == "" {
+		return id
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resolved[key] = true
+
+	if stored, ok := s.ids[key]; ok {
+		return stored
+	}
+
+	if prev, ok := s.otherRoute(key); ok {
+		stored := s.ids[prev]
+		s.remove(prev)
+		s.set(key, stored)
+		return stored
+	}
+
+	if legacy, ok := legacyOf(key); ok {
+		if stored, ok := s.ids[legacy]; ok {
+			s.remove(legacy)
+			s.set(key, stored)
+			return stored
+		}
+	}
+
+	if owner, ok := s.owners[id]; ok {
+		log.Warnf("Идентификатор предложения %s позиции %s уже закреплён за позицией %s", id, key, owner)
+		return id
+	}
+
+	s.set(key, id)
+
+	return id
+}
+
+// otherRoute возвращает единственный ключ позиции с тем же брендом, артикулом и кодом склада,
+// что и key, но другим маршрутом, не запрошенный после последнего Save.
+func (s *Store) otherRoute(key string) (string, bool) {
+
+	item, ok := itemOf(key)
+	if !ok {
+		return "", false
+	}
+
+	var found string
+	for other := range s.items[item] {
+		if other == key || s.resolved[other] {
+			continue
+		}
+		if found != "" {
+			return "", false
+		}
+		found = other
+	}
+
+	return found, found != ""
+}
+
+// set закрепляет за позицией key идентификатор id.
+func (s *Store) set(key, id string) {
+
+	s.ids[key] = id
+	s.owners[id] = key
+	if item, ok := itemOf(key); ok {
+		if s.items[item] == nil {
+			s.items[item] = make(map[string]bool)
+		}
+		s.items[item][key] = true
+	}
+	s.dirty = true
+}
+
+// remove удаляет позицию key, не освобождая её идентификатор.
+func (s *Store) remove(key string) {
+
+	delete(s.ids, key)
+	if item, ok := itemOf(key); ok {
+		delete(s.items[item], key)
+		if len(s.items[item]) == 0 {
+			delete(s.items, item)
+		}
+	}
+	s.dirty = true
+}
+
+// Assign закрепляет за позицией key идентификатор id вместо прежнего, например идентификатор
+// с суффиксом, полученный при совпадении идентификаторов позиций. Прежний идентификатор позиции освобождается.
+// Возвращает false, если id уже закреплён за другой позицией.
+func (s *Store) Assign(key, id string) bool {
+
+	if s == nil || id == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if owner, ok := s.owners[id]; ok {
+		return owner == key
+	}
+
+	if prev, ok := s.ids[key]; ok {
+		delete(s.owners, prev)
+	}
+	s.set(key, id)
+
+	return true
+}
+
+// fileModTime возвращает время изменения файла хранилища; для отсутствующего файла - нулевое время.
+func (s *Store) fileModTime() (time.Time, error) {
+
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+// readFile читает соответствия из файла хранилища; отсутствующий файл означает пустое хранилище.
+func (s *Store) readFile() (map[string]string, error) {
+
+	ids := make(map[string]string)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, errors.Wrapf(err, "разбор файла %s", s.path)
+	}
+
+	return ids, nil
+}
+
+// load загружает хранилище из файла, если файл изменён после последней загрузки или сохранения.
+// Несохранённые соответствия хранилища заменяются содержимым файла.
+func (s *Store) load() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	modTime, err := s.fileModTime()
+	if err != nil {
+		return err
+	}
+	if modTime.Equal(s.modTime) {
+		return nil
+	}
+
+	ids, err := s.readFile()
+	if err != nil {
+		return err
+	}
+
+	if s.dirty {
+		log.Warnf("Файл идентификаторов предложений %s изменён, несохранённые идентификаторы заменены", s.path)
+	}
+
+	s.ids, s.owners, s.items = make(map[string]string), make(map[string]string), make(map[string]map[string]bool)
+	if err := s.merge(ids); err != nil {
+		return err
+	}
+	s.modTime, s.dirty = modTime, false
+
+	return nil
+}
+
+// merge добавляет соответствия ids, заменяя идентификаторы тех же ключей.
+// Если после замены один идентификатор закреплён за двумя ключами, хранилище не меняется.
+func (s *Store) merge(ids map[string]string) error {
+
+	merged := make(map[string]string, len(s.ids)+len(ids))
+	for key, id := range s.ids {
+		merged[key] = id
+	}
+	for key, id := range ids {
+		if id == "" {
+			return errors.Errorf("пустой идентификатор позиции %s", key)
+		}
+		merged[key] = id
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	owners := make(map[string]string, len(merged))
+	items := make(map[string]map[string]bool)
+	for _, key := range keys {
+		id := merged[key]
+		if owner, ok := owners[id]; ok {
+			return errors.Errorf("идентификатор %s закреплён за позициями %s и %s", id, owner, key)
+		}
+		owners[id] = key
+		if item, ok := itemOf(key); ok {
+			if items[item] == nil {
+				items[item] = make(map[string]bool)
+			}
+			items[item][key] = true
+		}
+	}
+
+	s.ids, s.owners, s.items = merged, owners, items
+	s.dirty = s.dirty || len(ids) != 0
+
+	return nil
+}
+
+// Save сохраняет хранилище в файл. Файл заменяется целиком, чтобы прерванная запись не повредила его.
+// Если файл изменён после загрузки хранилища, соответствия файла заменяют соответствия тех же позиций
+// хранилища; при противоречии файл не перезаписывается. Save завершает формирование фида:
+// идентификаторы запрошенных позиций снова могут перейти к позициям других маршрутов (см. Resolve).
+func (s *Store) Save() error {
+
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resolved = make(map[string]bool)
+	if !s.dirty {
+		return nil
+	}
+
+	modTime, err := s.fileModTime()
+	if err != nil {
+		return err
+	}
+	if !modTime.Equal(s.modTime) {
+		ids, err := s.readFile()
+		if err != nil {
+			return err
+		}
+		if err := s.merge(ids); err != nil {
+			return errors.Wrapf(err, "файл %s изменён после загрузки", s.path)
+		}
+	}
+
+	data, err := json.Marshal(s.ids)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if s.modTime, err = s.fileModTime(); err != nil {
+		return err
+	}
+	s.dirty = false
+
+	return nil
+}
+
+// Close сохраняет хранилище после формирования фида, ошибка сохранения записывается в журнал.
+func (s *Store) Close() {
+
+	if err := s.Save(); err != nil {
+		log.Errorf("Не удалось сохранить идентификаторы предложений в %s: %v", s.path, err)
+	}
+}
+
+// Export выгружает хранилище в w в формате CSV "бренд;артикул;маршрут;код склада;идентификатор".
+// У позиций прежнего формата маршрут и код склада не заполнены.
+func (s *Store) Export(w io.Writer) error {
+
+	if s == nil {
+		return errors.New("не задан файл идентификаторов предложений")
+	}
+
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.ids))
+	for key := range s.ids {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		row := strings.Split(key, keySep)
+		if len(row) == 2 {
+			row = append(row, "", "")
+		}
+		rows = append(rows, append(row, s.ids[key]))
+	}
+	s.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	return cw.WriteAll(rows)
+}
+
+// Import загружает в хранилище соответствия из CSV "бренд;артикул;маршрут;код склада;идентификатор",
+// например выгруженные Export или подготовленные по действующим объявлениям.
+// Строки "бренд;артикул;идентификатор" и строки без маршрута загружаются в прежнем формате
+// и переходят к позиции при первом обращении (см. Resolve).
+// Идентификаторы тех же позиций заменяются. При ошибке хранилище не меняется.
+// Возвращает количество загруженных соответствий.
+func (s *Store) Import(r io.Reader) (int, error) {
+
+	if s == nil {
+		return 0, errors.New("не задан файл идентификаторов предложений")
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comma = ';'
+	cr.FieldsPerRecord = -1
+
+	ids := make(map[string]string)
+	for line := 1; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "разбор идентификаторов предложений")
+		}
+
+		var key, id string
+		switch {
+		case len(row) == 3:
+			key, id = legacyKey(row[0], row[1]), row[2]
+		case len(row) == 5 && strings.TrimSpace(row[2]) == "":
+			key, id = legacyKey(row[0], row[1]), row[4]
+		case len(row) == 5:
+			route, err := strconv.Atoi(strings.TrimSpace(row[2]))
+			if err != nil {
+				return 0, errors.Errorf("строка %d: неверный маршрут %q", line, row[2])
+			}
+			key, id = Key(row[0], row[1], route, row[3]), row[4]
+		default:
+			return 0, errors.Errorf("строка %d: ожидается 3 или 5 полей, получено %d", line, len(row))
+		}
+
+		id = strings.TrimSpace(id)
+		if prev, ok := ids[key]; ok && prev != id {
+			return 0, errors.Errorf("строка %d: позиции %s указаны идентификаторы %s и %s", line, key, prev, id)
+		}
+		ids[key] = id
+	}
+
+	s.mu.Lock()
+	err := s.merge(ids)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ids), s.Save()
+}
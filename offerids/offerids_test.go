@@ -0,0 +1,253 @@
+package offerids
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStoreResolve(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "offer-ids.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	route1 := Key("Mann Filter", "W712/95", 1, "")
+	if got := s.Resolve(route1, "mann-1"); got != "mann-1" {
+		t.Errorf("Resolve() = %q, want current mode ID", got)
+	}
+	if got := s.Resolve(route1, "Mann Filter_W712/95"); got != "mann-1" {
+		t.Errorf("Resolve() after mode change = %q, want %q", got, "mann-1")
+	}
+
+	// Позиция другого маршрута - другое объявление.
+	route2 := Key("Mann Filter", "W712/95", 2, "")
+	if got := s.Resolve(route2, "mann-2"); got != "mann-2" {
+		t.Errorf("Resolve() for other route = %q, want %q", got, "mann-2")
+	}
+
+	// Идентификатор, закреплённый за другой позицией, не закрепляется повторно.
+	code := Key("Mann Filter", "W712/95", 1, "WH1")
+	if got := s.Resolve(code, "mann-1"); got != "mann-1" || s.ids[code] != "" {
+		t.Errorf("Resolve() with taken ID = %q, stored %q", got, s.ids[code])
+	}
+
+	// Закрепление другого идентификатора освобождает прежний.
+	if !s.Assign(route2, "mann-2-b") || s.Assign(route2, "mann-1") {
+		t.Errorf("Assign() = %v", s.ids)
+	}
+	if _, ok := s.owners["mann-2"]; ok {
+		t.Errorf("Assign() kept previous ID owner")
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded := newStore(path)
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if diff := cmp.Diff(s.ids, loaded.ids); diff != "" {
+		t.Errorf("load() mismatch (-want +got):\n%s", diff)
+	}
+
+	var none *Store
+	if got := none.Resolve(route1, "id"); got != "id" {
+		t.Errorf("Resolve() without store = %q", got)
+	}
+}
+
+func TestStoreLegacyKeys(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "offer-ids.json")
+	if err := os.WriteFile(path, []byte(`{"MOBIL|152566":"Mobil_152566","BOSCH|0986":"bosch-1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	// Прежний идентификатор переходит к первой позиции бренда и артикула,
+	// позиция другого маршрута получает свой идентификатор.
+	first := Key("Mobil", "152566", 3, "")
+	if got := s.Resolve(first, "mobil-3"); got != "Mobil_152566" {
+		t.Errorf("Resolve() for legacy key = %q, want %q", got, "Mobil_152566")
+	}
+	if got := s.Resolve(Key("Mobil", "152566", 4, ""), "mobil-4"); got != "mobil-4" {
+		t.Errorf("Resolve() for other route = %q, want %q", got, "mobil-4")
+	}
+
+	want := map[string]string{
+		"BOSCH|0986":      "bosch-1",
+		"MOBIL|152566|3|": "Mobil_152566",
+		"MOBIL|152566|4|": "mobil-4",
+	}
+	if diff := cmp.Diff(want, s.ids); diff != "" {
+		t.Errorf("ids mismatch (-want +got):\n%s", diff)
+	}
+
+	// Новый идентификатор не совпадает с неперенесённым прежним.
+	if got := s.Resolve(Key("Bosch", "1", 0, ""), "bosch-1"); got != "bosch-1" || s.ids["BOSCH|1|0|"] != "" {
+		t.Errorf("Resolve() with legacy ID = %q, stored %q", got, s.ids["BOSCH|1|0|"])
+	}
+}
+
+func TestStoreRouteChange(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "offer-ids.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	s.Resolve(Key("Mann Filter", "W712/95", 1, ""), "mann-1")
+	s.Resolve(Key("Bosch", "0986", 1, "WH1"), "bosch-1")
+	s.Resolve(Key("Bosch", "0986", 2, "WH1"), "bosch-2")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Клиент сменил маршрут: позиция сохраняет идентификатор.
+	moved := Key("Mann Filter", "W712/95", 7, "")
+	if got := s.Resolve(moved, "mann-7"); got != "mann-1" {
+		t.Errorf("Resolve() after route change = %q, want %q", got, "mann-1")
+	}
+	if _, ok := s.ids[Key("Mann Filter", "W712/95", 1, "")]; ok {
+		t.Errorf("Resolve() kept previous route key: %v", s.ids)
+	}
+
+	// Идентификатор, уже запрошенный позицией в этом формировании фида, не переходит.
+	if got := s.Resolve(Key("Mann Filter", "W712/95", 8, ""), "mann-8"); got != "mann-8" {
+		t.Errorf("Resolve() for requested route = %q, want %q", got, "mann-8")
+	}
+
+	// Из нескольких маршрутов идентификатор не выбирается.
+	if got := s.Resolve(Key("Bosch", "0986", 3, "WH1"), "bosch-3"); got != "bosch-3" {
+		t.Errorf("Resolve() with several routes = %q, want %q", got, "bosch-3")
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "offer-ids.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s.Resolve(Key("Mobil", "152566", 0, ""), "mobil-1")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Загрузка командой offerids между формированиями фида.
+	imported := `{"MOBIL|152566|0|":"Mobil_152566"}`
+	if err := os.WriteFile(path, []byte(imported), 0600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 1)
+
+	if s, err = Open(path); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := s.Resolve(Key("Mobil", "152566", 0, ""), "mobil-1"); got != "Mobil_152566" {
+		t.Errorf("Resolve() after import = %q, want %q", got, "Mobil_152566")
+	}
+
+	// Загрузка во время формирования фида не перезаписывается.
+	s.Resolve(Key("Bosch", "0986", 0, ""), "bosch-1")
+	imported = `{"MOBIL|152566|0|":"mobil-2"}`
+	if err := os.WriteFile(path, []byte(imported), 0600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 2)
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	want := map[string]string{"MOBIL|152566|0|": "mobil-2", "BOSCH|0986|0|": "bosch-1"}
+	loaded := newStore(path)
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if diff := cmp.Diff(want, loaded.ids); diff != "" {
+		t.Errorf("Save() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// touch сдвигает время изменения файла path на shift секунд вперёд,
+// чтобы изменение было заметно при грубой точности времени файловой системы.
+func touch(t *testing.T, path string, shift int) {
+
+	mt := time.Now().Add(time.Duration(shift) * time.Second)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreExportImport(t *testing.T) {
+
+	dir := t.TempDir()
+	src, _ := Open(filepath.Join(dir, "src.json"))
+	dst, _ := Open(filepath.Join(dir, "dst.json"))
+
+	src.Resolve(Key("Mobil", "152566", 0, ""), "Mobil_152566")
+	src.Resolve(Key("Bosch", "0 986 452 041", 2, "wh1"), "Bosch-0 986 452 041")
+	if err := src.merge(map[string]string{"CASTROL|15669E": "castrol-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := "BOSCH;0 986 452 041;2;WH1;Bosch-0 986 452 041\nCASTROL;15669E;;;castrol-1\nMOBIL;152566;0;;Mobil_152566\n"
+	if buf.String() != want {
+		t.Errorf("Export() = %q, want %q", buf.String(), want)
+	}
+
+	n, err := dst.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil || n != 3 {
+		t.Fatalf("Import() = %d, %v, want 3", n, err)
+	}
+	if diff := cmp.Diff(src.ids, dst.ids); diff != "" {
+		t.Errorf("Import() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Строки прежнего формата загружаются без маршрута.
+	if _, err := dst.Import(bytes.NewBufferString("LIQUI MOLY;2;lm-2\n")); err != nil {
+		t.Fatalf("Import() legacy rows error = %v", err)
+	}
+	if got := dst.Resolve(Key("liqui moly", "2", 5, ""), "new"); got != "lm-2" {
+		t.Errorf("Resolve() after legacy import = %q, want %q", got, "lm-2")
+	}
+
+	tests := []string{
+		"CASTROL;1;0;;Mobil_152566\n",         // идентификатор другой позиции
+		"CASTROL;1;0;;a\nCASTROL;1;0;;b\n",    // два идентификатора позиции
+		"CASTROL;1;0;;a\nLIQUI MOLY;3;0;;a\n", // один идентификатор двух позиций
+		"CASTROL;1;x;;a\n",                    // неверный маршрут
+		"CASTROL;1\n",                         // неполная строка
+		"CASTROL;1;0;;\n",                     // пустой идентификатор
+	}
+	for _, data := range tests {
+		if _, err := dst.Import(bytes.NewBufferString(data)); err == nil {
+			t.Errorf("Import(%q) error = nil, want error", data)
+		}
+	}
+
+	// Замена идентификатора позиции освобождает прежний.
+	if _, err := dst.Import(bytes.NewBufferString("MOBIL;152566;0;;mobil-1\nCASTROL;1;0;;Mobil_152566\n")); err != nil {
+		t.Errorf("Import() with reassignment error = %v", err)
+	}
+	if dst.owners["Mobil_152566"] != "CASTROL|1|0|" {
+		t.Errorf("Import() = %v", dst.ids)
+	}
+}
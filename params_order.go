@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// paramsOrderKey содержит ключ goodsGroupPack с порядком вывода параметров группы товаров:
+// имя свойства -> номер позиции в списке ("viscosity": "1").
+const paramsOrderKey = "paramsorder"
+
+// defaultParamsOrder задаёт порядок вывода параметров в описании по группам товаров,
+// если он не задан в goodsGroupPack.
+var defaultParamsOrder = map[string][]string{
+	"bicycles":        {"type", "age", "wheel_diameter", "frame_size", "speeds"},
+	"gear_oils":       {"viscosity", "atf_spec", "api_spec", "liquid_volume"},
+	"compressor_oils": {"liquid_volume"},
+	"oils":            {"viscosity", "liquid_volume", "acea_spec", "api_spec"},
+	"brake_fluids":    {"dot_spec", "liquid_volume"},
+	"coolant":         {"coolant_color", "liquid_volume"},
+	"batteries":       {"voltage", "capacity", "cca", "polarity", "length", "width", "height"},
+	"wheel_covers":    {"diameter"},
+	"moto_tires":      {"axle", "width", "height", "diameter", "holes", "pcd", "et"},
+	"truck_tires":     {"axle", "season", "width", "height", "diameter", "holes", "pcd", "et"},
+	"tires":           {"season", "axle", "width", "height", "diameter", "holes", "pcd", "et"},
+	"disks":           {"disk_type", "diameter", "width", "holes", "pcd", "et", "hub_diameter", "axle"},
+	"wipers":          {"construction", "connector", "length1", "length2", "pack_count"},
+}
+
+// paramsOrder содержит номера позиций свойств в списке параметров описания.
+// Свойства без номера выводятся после пронумерованных в алфавитном порядке названий.
+type paramsOrder map[string]int
+
+// newParamsOrder возвращает порядок вывода параметров группы товаров group.
+// Порядок из goodsGroupPack заменяет встроенный целиком.
+func newParamsOrder(goodsGroupPack map[string]map[string]map[string]string, group string) paramsOrder {
+
+	order := make(paramsOrder)
+	for prop, pos := range goodsGroupPack[group][paramsOrderKey] {
+		n, err := strconv.Atoi(strings.TrimSpace(pos))
+		if err != nil {
+			log.Warnf("Некорректная позиция параметра %s группы товаров %s: %q", prop, group, pos)
+			continue
+		}
+		order[prop] = n
+	}
+
+	if len(order) != 0 {
+		return order
+	}
+
+	for i, prop := range defaultParamsOrder[group] {
+		order[prop] = i + 1
+	}
+
+	return order
+}
+
+// namedParam связывает параметр описания со свойством, из которого он получен.
+type namedParam struct {
+	prop  string
+	param xmlParam
+}
+
+// sortParams возвращает параметры в порядке order.
+// Результат не зависит от порядка обхода свойств.
+func sortParams(params []namedParam, order paramsOrder) []xmlParam {
+
+	sort.Slice(params, func(i, j int) bool {
+		a, b := params[i], params[j]
+		posA, okA := order[a.prop]
+		posB, okB := order[b.prop]
+
+		switch {
+		case okA && okB && posA != posB:
+			return posA < posB
+		case okA != okB:
+			return okA
+		case a.param.Name != b.param.Name:
+			return a.param.Name < b.param.Name
+		case a.prop != b.prop:
+			return a.prop < b.prop
+		default:
+			return a.param.Content < b.param.Content
+		}
+	})
+
+	out := make([]xmlParam, 0, len(params))
+	for _, p := range params {
+		out = append(out, p.param)
+	}
+
+	return out
+}
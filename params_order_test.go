@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewParamsOrder(t *testing.T) {
+
+	goodsGroupPack := map[string]map[string]map[string]string{
+		"oils": {
+			paramsOrderKey: {"api_spec": "1", "viscosity": " 2 ", "liquid_volume": "first"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		group string
+		want  paramsOrder
+	}{
+		{name: "Configured order replaces default", group: "oils", want: paramsOrder{"api_spec": 1, "viscosity": 2}},
+		{name: "Default order", group: "brake_fluids", want: paramsOrder{"dot_spec": 1, "liquid_volume": 2}},
+		{name: "Unknown group", group: "filters", want: paramsOrder{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newParamsOrder(goodsGroupPack, tt.group); !cmp.Equal(got, tt.want) {
+				t.Errorf("newParamsOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortParams(t *testing.T) {
+
+	order := paramsOrder{"viscosity": 1, "liquid_volume": 2}
+	want := []xmlParam{
+		{Name: "SAE", Content: "5W-30"},
+		{Name: "Volume", Content: "4"},
+		{Name: "API", Content: "SN"},
+		{Name: "Бренд", Content: "A"},
+		{Name: "Бренд", Content: "B"},
+	}
+
+	// Порядок результата не должен зависеть от порядка входных параметров.
+	inputs := [][]namedParam{
+		{
+			{"brand2", xmlParam{Name: "Бренд", Content: "B"}},
+			{"api_spec", xmlParam{Name: "API", Content: "SN"}},
+			{"liquid_volume", xmlParam{Name: "Volume", Content: "4"}},
+			{"brand1", xmlParam{Name: "Бренд", Content: "A"}},
+			{"viscosity", xmlParam{Name: "SAE", Content: "5W-30"}},
+		},
+		{
+			{"viscosity", xmlParam{Name: "SAE", Content: "5W-30"}},
+			{"brand1", xmlParam{Name: "Бренд", Content: "A"}},
+			{"liquid_volume", xmlParam{Name: "Volume", Content: "4"}},
+			{"api_spec", xmlParam{Name: "API", Content: "SN"}},
+			{"brand2", xmlParam{Name: "Бренд", Content: "B"}},
+		},
+	}
+
+	for _, params := range inputs {
+		if got := sortParams(params, order); !cmp.Equal(got, want) {
+			t.Errorf("sortParams() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// placeholderParams содержит заглушки клиента для предложений без изображений.
+// Значение - имя изображения, которое формируется поставщиком изображений клиента,
+// или полная ссылка на изображение.
+type placeholderParams struct {
+	Default     string            `json:"default"`
+	GoodsGroups map[string]string `json:"goodsGroups"` // группа товаров -> заглушка
+	Brands      map[string]string `json:"brands"`      // бренд -> заглушка
+}
+
+// placeholderImages выбирает заглушку позиции: по бренду, по группе товаров, заглушку клиента по умолчанию.
+// Пустой результат означает встроенную заглушку поставщика изображений.
+type placeholderImages struct {
+	def         string
+	goodsGroups map[string]string
+	brands      map[string]string
+}
+
+func newPlaceholderImages(params *placeholderParams) placeholderImages {
+
+	var p placeholderImages
+	if params == nil {
+		return p
+	}
+
+	p.def = strings.TrimSpace(params.Default)
+	p.goodsGroups = make(map[string]string, len(params.GoodsGroups))
+	for group, name := range params.GoodsGroups {
+		if name = strings.TrimSpace(name); name != "" {
+			p.goodsGroups[strings.TrimSpace(group)] = name
+		}
+	}
+	p.brands = make(map[string]string, len(params.Brands))
+	for brand, name := range params.Brands {
+		if name = strings.TrimSpace(name); name != "" {
+			p.brands[strings.ToUpper(strings.TrimSpace(brand))] = name
+		}
+	}
+
+	return p
+}
+
+// lookup возвращает заглушку клиента для позиции бренда brand группы товаров group.
+func (p placeholderImages) lookup(brand, group string) string {
+
+	if name, ok := p.brands[strings.ToUpper(strings.TrimSpace(brand))]; ok {
+		return name
+	}
+
+	if name, ok := p.goodsGroups[group]; ok {
+		return name
+	}
+
+	return p.def
+}
+
+// placeholderURL возвращает ссылку на заглушку позиции pos группы товаров group.
+// Полная ссылка из настроек клиента используется без изменений,
+// имя изображения передаётся поставщику изображений.
+func (b *offerBuilder) placeholderURL(pos position, group string) (*url.URL, bool) {
+
+	name := b.placeholders.lookup(pos.Brand, group)
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		u, err := url.Parse(name)
+		return u, err == nil
+	}
+
+	return b.placeholderProvider.PlaceholderURL(ImageRef{Name: name, Brand: pos.Brand, Number: pos.Number})
+}
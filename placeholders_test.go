@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestPlaceholderURL(t *testing.T) {
+
+	params := &placeholderParams{
+		Default:     "photo-on-request.jpg",
+		GoodsGroups: map[string]string{"tires": "https://cdn.example.com/tires.png"},
+		Brands:      map[string]string{"Mann Filter": "mann.jpg", "Bosch": " "},
+	}
+
+	tests := []struct {
+		name   string
+		avito  avitoParams
+		params *placeholderParams
+		pos    position
+		group  string
+		want   string
+	}{
+		{
+			name:   "Brand on pubimg",
+			params: params,
+			pos:    position{Brand: "MANN FILTER", Number: "W712/95"},
+			group:  "tires",
+			want:   "https://pubimg.nodacdn.net/images/mann0005.jpg",
+		},
+		{
+			name:   "Goods group full URL",
+			params: params,
+			pos:    position{Brand: "Bosch", Number: "0 986"},
+			group:  "tires",
+			want:   "https://cdn.example.com/tires.png",
+		},
+		{
+			name:   "Client default on autorus",
+			avito:  avitoParams{AlternativeImageProxy: "https://img.autorus.ru"},
+			params: params,
+			pos:    position{Brand: "Bosch", Number: "0 986"},
+			group:  "oils",
+			want:   "https://img.autorus.ru/images/bosch/0986/full/photo-on-request.jpg",
+		},
+		{
+			name:  "Provider placeholder without client settings",
+			avito: avitoParams{AlternativeImageProxy: "https://img.autorus.ru"},
+			pos:   position{Brand: "Bosch", Number: "0 986"},
+			want:  "https://img.autorus.ru/images/bosch/0986/full/05c40c050e1eeef58efb8bcf8e6ce2510b.png",
+		},
+		{
+			name: "No placeholder on pubimg without client settings",
+			pos:  position{Brand: "Bosch", Number: "0 986"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &offerBuilder{avito: &tt.avito, pfx: "0005", placeholders: newPlaceholderImages(tt.params)}
+
+			var err error
+			b.imageProvider, b.placeholderProvider, err = newImageURLProviders(&tt.avito, b.pfx)
+			if err != nil {
+				t.Fatalf("newImageURLProviders() error = %v", err)
+			}
+
+			got := ""
+			if u, ok := b.placeholderURL(tt.pos, tt.group); ok {
+				got = b.imageURL(u)
+			}
+			if got != tt.want {
+				t.Errorf("placeholderURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// promotionRuleParams описывает правило платного продвижения объявлений.
+// Пустые условия не ограничивают выбор позиций.
+type promotionRuleParams struct {
+	AdStatus    int      `json:"adStatus"` // код статуса, см. adsConst
+	Brands      []string `json:"brands"`
+	GoodsGroups []string `json:"goodsGroups"`
+	MinPrice    float64  `json:"minPrice"`
+	MaxPrice    float64  `json:"maxPrice"` // 0 - без ограничения
+	Weekdays    []int    `json:"weekdays"` // 1 - понедельник ... 7 - воскресенье
+	DateFrom    string   `json:"dateFrom"` // ГГГГ-ММ-ДД включительно
+	DateTo      string   `json:"dateTo"`   // ГГГГ-ММ-ДД включительно
+}
+
+// promotionParams содержит правила платного продвижения клиента.
+type promotionParams struct {
+	Rules []promotionRuleParams `json:"rules"`
+	// MaxPromoted ограничивает количество продвигаемых объявлений задачи, 0 - без ограничения.
+	// При ограничении продвижение получают самые дорогие из подходящих объявлений: до конца задачи
+	// в памяти удерживается не более MaxPromoted объявлений, остальные выгружаются с каждым пакетом.
+	MaxPromoted int `json:"maxPromoted"`
+}
+
+// promotionRule содержит разобранное правило продвижения.
+type promotionRule struct {
+	status   string
+	brands   map[string]bool
+	groups   map[string]bool
+	minPrice float64
+	maxPrice float64
+	weekdays map[time.Weekday]bool
+	from, to string // ГГГГ-ММ-ДД, строки сравниваются как даты
+}
+
+// promotionRules назначает статус объявления (тег <AdStatus>) по первому подходящему правилу.
+// Позиции, не подходящие ни под одно правило, получают общий статус AdStatus клиента.
+type promotionRules struct {
+	rules []promotionRule
+	max   int
+}
+
+// newPromotionRules разбирает правила продвижения params. Без правил возвращает nil.
+func newPromotionRules(params *promotionParams) (*promotionRules, error) {
+
+	if params == nil || len(params.Rules) == 0 {
+		return nil, nil
+	}
+
+	p := &promotionRules{max: params.MaxPromoted}
+	for i, rp := range params.Rules {
+		status, ok := adsConst[rp.AdStatus]
+		if !ok {
+			return nil, errors.Errorf("правило продвижения %d: неизвестный статус %d", i+1, rp.AdStatus)
+		}
+
+		r := promotionRule{status: status, minPrice: rp.MinPrice, maxPrice: rp.MaxPrice}
+		r.brands = upperSet(rp.Brands)
+		r.groups = make(map[string]bool, len(rp.GoodsGroups))
+		for _, group := range rp.GoodsGroups {
+			r.groups[strings.TrimSpace(group)] = true
+		}
+
+		r.weekdays = make(map[time.Weekday]bool, len(rp.Weekdays))
+		for _, day := range rp.Weekdays {
+			if day < 1 || day > 7 {
+				return nil, errors.Errorf("правило продвижения %d: неверный день недели %d", i+1, day)
+			}
+			r.weekdays[time.Weekday(day%7)] = true
+		}
+
+		for _, date := range []string{rp.DateFrom, rp.DateTo} {
+			if _, err := time.Parse(time.DateOnly, date); date != "" && err != nil {
+				return nil, errors.Wrapf(err, "правило продвижения %d", i+1)
+			}
+		}
+		r.from, r.to = rp.DateFrom, rp.DateTo
+
+		p.rules = append(p.rules, r)
+	}
+
+	return p, nil
+}
+
+// upperSet возвращает множество значений values в верхнем регистре.
+func upperSet(values []string) map[string]bool {
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToUpper(strings.TrimSpace(v))] = true
+	}
+
+	return set
+}
+
+// status возвращает статус продвижения позиции pos в момент now.
+func (p *promotionRules) status(pos position, now time.Time) (string, bool) {
+
+	if p == nil {
+		return "", false
+	}
+
+	for _, r := range p.rules {
+		if r.matches(pos, now) {
+			return r.status, true
+		}
+	}
+
+	return "", false
+}
+
+func (r promotionRule) matches(pos position, now time.Time) bool {
+
+	if len(r.brands) != 0 && !r.brands[strings.ToUpper(strings.TrimSpace(pos.Brand))] {
+		return false
+	}
+	if len(r.groups) != 0 && !r.groups[pos.GoodsGroupCode] {
+		return false
+	}
+	if pos.PriceSale < r.minPrice || (r.maxPrice > 0 && pos.PriceSale > r.maxPrice) {
+		return false
+	}
+	if len(r.weekdays) != 0 && !r.weekdays[now.Weekday()] {
+		return false
+	}
+
+	today := now.Format(time.DateOnly)
+	if (r.from != "" && today < r.from) || (r.to != "" && today > r.to) {
+		return false
+	}
+
+	return true
+}
+
+// maxPromoted возвращает ограничение количества продвигаемых объявлений, 0 - без ограничения.
+func (p *promotionRules) maxPromoted() int {
+
+	if p == nil {
+		return 0
+	}
+
+	return p.max
+}
+
+// promotionSummary ограничивает количество продвигаемых объявлений задачи
+// и подсчитывает их для журнала.
+type promotionSummary struct {
+	base     string
+	max      int
+	held     promotedOffers
+	promoted map[string]int
+	demoted  int
+}
+
+// newPromotionSummary возвращает сводку продвижения с общим статусом base
+// и ограничением max продвигаемых объявлений (0 - без ограничения).
+func newPromotionSummary(base string, max int) *promotionSummary {
+	return &promotionSummary{base: base, max: max, promoted: make(map[string]int)}
+}
+
+// pricedOffer содержит объявление и цену позиции, по которой выбираются продвигаемые объявления.
+// Цена объявления не используется: при HidePriceTag она не заполняется.
+type pricedOffer struct {
+	offer xmlOffer
+	price float64
+}
+
+// admit возвращает объявления пакета offers, которые можно выгрузить сразу.
+// При ограничении продвижения удерживаются max самых дорогих продвигаемых объявлений задачи,
+// вытесненные из них объявления выгружаются с общим статусом.
+func (s *promotionSummary) admit(offers []pricedOffer) []xmlOffer {
+
+	out := make([]xmlOffer, 0, len(offers))
+	for _, o := range offers {
+		if s.max <= 0 || o.offer.AdStatus == s.base {
+			out = append(out, o.offer)
+			continue
+		}
+
+		if s.held.Len() == s.max {
+			if !morePromoted(o, s.held[0]) {
+				out = append(out, s.demote(o.offer))
+				continue
+			}
+			out = append(out, s.demote(heap.Pop(&s.held).(pricedOffer).offer))
+		}
+		heap.Push(&s.held, o)
+	}
+
+	return out
+}
+
+// flush возвращает удержанные продвигаемые объявления после обработки всех пакетов.
+func (s *promotionSummary) flush() []xmlOffer {
+
+	held := make([]xmlOffer, 0, len(s.held))
+	for _, o := range s.held {
+		held = append(held, o.offer)
+	}
+	s.held = nil
+
+	return held
+}
+
+func (s *promotionSummary) demote(offer xmlOffer) xmlOffer {
+
+	offer.AdStatus = s.base
+	s.demoted++
+
+	return offer
+}
+
+// morePromoted сообщает, имеет ли объявление a преимущество перед b при ограничении продвижения:
+// дороже или, при равной цене, с меньшим идентификатором.
+func morePromoted(a, b pricedOffer) bool {
+
+	if a.price != b.price {
+		return a.price > b.price
+	}
+
+	return a.offer.ID < b.offer.ID
+}
+
+// promotedOffers - куча продвигаемых объявлений, в корне которой объявление с наименьшим преимуществом.
+type promotedOffers []pricedOffer
+
+func (h promotedOffers) Len() int           { return len(h) }
+func (h promotedOffers) Less(i, j int) bool { return morePromoted(h[j], h[i]) }
+func (h promotedOffers) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *promotedOffers) Push(x any) { *h = append(*h, x.(pricedOffer)) }
+
+func (h *promotedOffers) Pop() any {
+
+	old := *h
+	offer := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return offer
+}
+
+// count учитывает продвигаемые объявления выгружаемого пакета offers.
+func (s *promotionSummary) count(offers []xmlOffer) {
+
+	for _, offer := range offers {
+		if offer.AdStatus != s.base {
+			s.promoted[offer.AdStatus]++
+		}
+	}
+}
+
+// log выводит сводку продвижения. Параметр task идентифицирует задачу в журнале.
+func (s *promotionSummary) log(task string) {
+
+	if len(s.promoted) == 0 && s.demoted == 0 {
+		return
+	}
+
+	total := 0
+	statuses := make([]string, 0, len(s.promoted))
+	for _, status := range sortedKeys(s.promoted) {
+		statuses = append(statuses, fmt.Sprintf("%s %d", status, s.promoted[status]))
+		total += s.promoted[status]
+	}
+
+	log.Printf("%s: продвигаемых объявлений %d (%s), без продвижения из-за ограничения: %d",
+		task, total, strings.Join(statuses, ", "), s.demoted)
+}
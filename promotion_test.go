@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPromotionRules(t *testing.T) {
+
+	rules, err := newPromotionRules(&promotionParams{Rules: []promotionRuleParams{
+		{AdStatus: 2, Brands: []string{"Mobil", " castrol "}, MinPrice: 3000},
+		{AdStatus: 7, GoodsGroups: []string{"tires"}, Weekdays: []int{5, 6, 7}},
+		{AdStatus: 10, MaxPrice: 500, DateFrom: "2026-11-01", DateTo: "2026-11-30"},
+	}})
+	if err != nil {
+		t.Fatalf("newPromotionRules() error = %v", err)
+	}
+
+	friday := time.Date(2026, 10, 30, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 11, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		pos  position
+		now  time.Time
+		want string
+	}{
+		{name: "Brand and price", pos: position{Brand: "CASTROL", PriceSale: 3500}, now: friday, want: "VIP"},
+		{name: "Brand below price", pos: position{Brand: "Castrol", PriceSale: 2500}, now: friday},
+		{name: "Goods group on weekend", pos: position{Brand: "Nokian", GoodsGroupCode: "tires", PriceSale: 9000}, now: friday, want: "XL"},
+		{name: "Goods group on weekday", pos: position{Brand: "Nokian", GoodsGroupCode: "tires", PriceSale: 9000}, now: monday},
+		{name: "Cheap in date window", pos: position{Brand: "Bosch", PriceSale: 400}, now: monday, want: "x5_1"},
+		{name: "Cheap before date window", pos: position{Brand: "Bosch", PriceSale: 400}, now: friday},
+		{name: "First rule wins", pos: position{Brand: "Mobil", GoodsGroupCode: "tires", PriceSale: 3000}, now: friday, want: "VIP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := rules.status(tt.pos, tt.now)
+			if got != tt.want {
+				t.Errorf("status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var none *promotionRules
+	if _, ok := none.status(position{}, friday); ok || none.maxPromoted() != 0 {
+		t.Error("status() without rules = true")
+	}
+
+	invalid := []promotionParams{
+		{Rules: []promotionRuleParams{{AdStatus: 99}}},
+		{Rules: []promotionRuleParams{{AdStatus: 1, Weekdays: []int{0}}}},
+		{Rules: []promotionRuleParams{{AdStatus: 1, DateTo: "30.11.2026"}}},
+	}
+	for _, params := range invalid {
+		if _, err := newPromotionRules(&params); err == nil {
+			t.Errorf("newPromotionRules(%+v) error = nil, want error", params)
+		}
+	}
+}
+
+func TestPromotionSummaryLimit(t *testing.T) {
+
+	// Цена объявлений не заполнена, как при HidePriceTag: выбор по цене позиции.
+	batches := [][]pricedOffer{
+		{
+			{offer: xmlOffer{ID: "a", AdStatus: "VIP"}, price: 1000},
+			{offer: xmlOffer{ID: "b", AdStatus: "XL"}, price: 5000},
+			{offer: xmlOffer{ID: "c", AdStatus: "Free"}, price: 9000},
+		},
+		{
+			{offer: xmlOffer{ID: "e", AdStatus: "XL"}, price: 3000},
+			{offer: xmlOffer{ID: "d", AdStatus: "VIP"}, price: 3000},
+		},
+	}
+
+	s := newPromotionSummary("Free", 2)
+
+	got := make(map[string]string)
+	add := func(content []xmlOffer) {
+		s.count(content)
+		for _, offer := range content {
+			got[offer.ID] = offer.AdStatus
+		}
+	}
+
+	emitted := s.admit(batches[0])
+	if len(emitted) != 1 || emitted[0].ID != "c" {
+		t.Errorf("admit() = %+v, want only c", emitted)
+	}
+	add(emitted)
+	add(s.admit(batches[1]))
+	add(s.flush())
+
+	want := map[string]string{"a": "Free", "b": "XL", "c": "Free", "d": "VIP", "e": "Free"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("admit() mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(map[string]int{"VIP": 1, "XL": 1}, s.promoted); diff != "" || s.demoted != 2 {
+		t.Errorf("count() mismatch (-want +got):\n%s, demoted %d", diff, s.demoted)
+	}
+
+	if held := s.flush(); len(held) != 0 {
+		t.Errorf("flush() after flush = %+v, want empty", held)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// propReader предоставляет типизированный доступ к свойствам товара из article api.
+// Значения приводятся к запрошенному типу, если это возможно без потери смысла
+// (число в строку, строка в число, первый элемент массива в строку).
+// Несоответствие типа не приводит к панике, а регистрируется в отчёте задачи
+// с привязкой к позиции бренд|артикул.
+type propReader struct {
+	props  map[string]any
+	key    string
+	report *feedReport
+}
+
+func newPropReader(props map[string]any, key string, report *feedReport) propReader {
+	return propReader{props: props, key: key, report: report}
+}
+
+// Has сообщает, задано ли свойство name.
+func (p propReader) Has(name string) bool {
+
+	v, ok := p.props[name]
+
+	return ok && v != nil
+}
+
+// Raw возвращает значение свойства name без приведения типа.
+func (p propReader) Raw(name string) (any, bool) {
+
+	v, ok := p.props[name]
+	if !ok || v == nil {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// String возвращает строковое значение свойства name.
+// Для массива возвращается первый элемент.
+func (p propReader) String(name string) (string, bool) {
+
+	v, ok := p.Raw(name)
+	if !ok {
+		return "", false
+	}
+
+	if arr, isArr := v.([]any); isArr {
+		if len(arr) == 0 {
+			return "", false
+		}
+		v = arr[0]
+	}
+
+	s, ok := scalarString(v)
+	if !ok {
+		p.report.addTypeMismatch(name, p.key, v)
+		return "", false
+	}
+
+	return s, true
+}
+
+// Strings возвращает значения свойства name в виде списка строк.
+// Скалярное значение возвращается как список из одного элемента,
+// элементы массива, которые не удалось привести к строке, пропускаются.
+func (p propReader) Strings(name string) ([]string, bool) {
+
+	v, ok := p.Raw(name)
+	if !ok {
+		return nil, false
+	}
+
+	var values []any
+	switch val := v.(type) {
+	case []any:
+		values = val
+	case []string:
+		return val, len(val) != 0
+	default:
+		values = []any{val}
+	}
+
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := scalarString(value)
+		if !ok {
+			p.report.addTypeMismatch(name, p.key, value)
+			continue
+		}
+		out = append(out, s)
+	}
+
+	return out, len(out) != 0
+}
+
+// Float возвращает числовое значение свойства name.
+// Строки разбираются с десятичным разделителем "точка" или "запятая".
+func (p propReader) Float(name string) (float64, bool) {
+
+	v, ok := p.Raw(name)
+	if !ok {
+		return 0, false
+	}
+
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(val), ",", ".", 1), 64)
+		if err == nil {
+			return f, true
+		}
+	}
+
+	p.report.addTypeMismatch(name, p.key, v)
+
+	return 0, false
+}
+
+// Int возвращает целочисленное значение свойства name.
+// Дробные значения округляются до ближайшего целого.
+func (p propReader) Int(name string) (int, bool) {
+
+	if v, ok := p.Raw(name); ok {
+		if i, isInt := v.(int); isInt {
+			return i, true
+		}
+	}
+
+	f, ok := p.Float(name)
+	if !ok {
+		return 0, false
+	}
+
+	return int(math.Round(f)), true
+}
+
+// scalarString приводит скалярное значение свойства к строке.
+func scalarString(v any) (string, bool) {
+
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32), true
+	case int:
+		return strconv.Itoa(val), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPropReader(t *testing.T) {
+
+	report := newFeedReport()
+	p := newPropReader(map[string]any{
+		"goods_group":   "oils",
+		"liquid_volume": 4.0,
+		"pack_count":    "2",
+		"length1":       "47,5",
+		"connector":     []any{"claw (клешня)", "pin lock (штырь)"},
+		"api_spec":      []any{"SN", 7, map[string]any{}},
+		"images":        []any{},
+		"voltage":       12,
+		"flag":          true,
+		"empty":         nil,
+	}, "BRAND|NUM", report)
+
+	if got, ok := p.String("goods_group"); got != "oils" || !ok {
+		t.Errorf("String(goods_group) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.String("liquid_volume"); got != "4" || !ok {
+		t.Errorf("String(liquid_volume) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.String("connector"); got != "claw (клешня)" || !ok {
+		t.Errorf("String(connector) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.String("images"); got != "" || ok {
+		t.Errorf("String(images) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.String("empty"); got != "" || ok {
+		t.Errorf("String(empty) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.Strings("api_spec"); !cmp.Equal(got, []string{"SN", "7"}) || !ok {
+		t.Errorf("Strings(api_spec) = %v, %v", got, ok)
+	}
+
+	if got, ok := p.Strings("goods_group"); !cmp.Equal(got, []string{"oils"}) || !ok {
+		t.Errorf("Strings(goods_group) = %v, %v", got, ok)
+	}
+
+	if got, ok := p.Int("pack_count"); got != 2 || !ok {
+		t.Errorf("Int(pack_count) = %d, %v", got, ok)
+	}
+
+	if got, ok := p.Int("voltage"); got != 12 || !ok {
+		t.Errorf("Int(voltage) = %d, %v", got, ok)
+	}
+
+	if got, ok := p.Float("length1"); got != 47.5 || !ok {
+		t.Errorf("Float(length1) = %v, %v", got, ok)
+	}
+
+	if got, ok := p.Float("goods_group"); got != 0 || ok {
+		t.Errorf("Float(goods_group) = %v, %v", got, ok)
+	}
+
+	if got, ok := p.String("flag"); got != "" || ok {
+		t.Errorf("String(flag) = %q, %v", got, ok)
+	}
+
+	if got, ok := p.Int("missing"); got != 0 || ok {
+		t.Errorf("Int(missing) = %d, %v", got, ok)
+	}
+
+	wantMismatches := map[string][]string{
+		"api_spec":    {"BRAND|NUM (map[string]interface {})"},
+		"goods_group": {"BRAND|NUM (string)"},
+		"flag":        {"BRAND|NUM (bool)"},
+	}
+	if diff := cmp.Diff(wantMismatches, report.mismatches); diff != "" {
+		t.Errorf("report.mismatches returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
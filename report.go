@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// feedReport накапливает замечания, выявленные в ходе формирования фида,
+// чтобы вывести их одной сводкой по завершении задачи.
+// Методы безопасны для вызова на nil-указателе.
+type feedReport struct {
+	// unmapped содержит значения свойств, для которых не нашлось
+	// соответствия в справочниках: свойство -> значение -> количество.
+	unmapped map[string]map[string]int
+	// missing содержит позиции с незаполненными обязательными свойствами:
+	// группа товаров -> свойство -> бренд|артикул.
+	missing map[string]map[string][]string
+	// mismatches содержит позиции, у которых значение свойства имеет неожиданный тип:
+	// свойство -> "бренд|артикул (тип)".
+	mismatches map[string][]string
+	// templateErrors содержит ошибки выполнения шаблонов клиента:
+	// шаблон -> "бренд|артикул: ошибка".
+	templateErrors map[string][]string
+	// missingImages содержит недоступные изображения: "бренд|артикул: url".
+	missingImages []string
+	// longTitles содержит позиции, название которых пришлось обрезать до titleRuneLimit:
+	// "бренд|артикул: название".
+	longTitles []string
+	// offerIDCollisions содержит совпадения идентификаторов предложений разных позиций:
+	// идентификатор -> позиции.
+	offerIDCollisions map[string][]string
+	// positionErrors содержит ошибки обработки позиций.
+	positionErrors []positionError
+}
+
+// maxReportedKeys ограничивает количество позиций, перечисляемых в журнале для одного замечания.
+const maxReportedKeys = 10
+
+func newFeedReport() *feedReport {
+	return &feedReport{
+		unmapped:          make(map[string]map[string]int),
+		missing:           make(map[string]map[string][]string),
+		mismatches:        make(map[string][]string),
+		templateErrors:    make(map[string][]string),
+		offerIDCollisions: make(map[string][]string),
+	}
+}
+
+// addUnmapped регистрирует значение свойства prop, которое не удалось сопоставить.
+func (r *feedReport) addUnmapped(prop, value string) {
+
+	if r == nil {
+		return
+	}
+
+	if r.unmapped[prop] == nil {
+		r.unmapped[prop] = make(map[string]int)
+	}
+	r.unmapped[prop][value]++
+}
+
+// addMissing регистрирует позицию key, у которой не заполнено обязательное свойство prop группы товаров group.
+func (r *feedReport) addMissing(group, prop, key string) {
+
+	if r == nil {
+		return
+	}
+
+	if r.missing[group] == nil {
+		r.missing[group] = make(map[string][]string)
+	}
+	r.missing[group][prop] = append(r.missing[group][prop], key)
+}
+
+// addTypeMismatch регистрирует значение свойства prop позиции key, тип которого не удалось привести к ожидаемому.
+func (r *feedReport) addTypeMismatch(prop, key string, value any) {
+
+	if r == nil {
+		return
+	}
+
+	r.mismatches[prop] = append(r.mismatches[prop], fmt.Sprintf("%s (%T)", key, value))
+}
+
+// addTemplateError регистрирует ошибку выполнения шаблона клиента name для позиции key.
+func (r *feedReport) addTemplateError(name, key string, err error) {
+
+	if r == nil {
+		return
+	}
+
+	r.templateErrors[name] = append(r.templateErrors[name], key+": "+err.Error())
+}
+
+// addMissingImage регистрирует недоступное изображение url позиции key.
+func (r *feedReport) addMissingImage(key, url string) {
+
+	if r == nil {
+		return
+	}
+
+	r.missingImages = append(r.missingImages, key+": "+url)
+}
+
+// addLongTitle регистрирует позицию key, название title которой пришлось обрезать.
+func (r *feedReport) addLongTitle(key, title string) {
+
+	if r == nil {
+		return
+	}
+
+	r.longTitles = append(r.longTitles, key+": "+title)
+}
+
+// addOfferIDCollision регистрирует совпадение идентификатора id позиций first и next.
+// Каждая позиция указывается для идентификатора один раз.
+func (r *feedReport) addOfferIDCollision(id, first, next string) {
+
+	if r == nil {
+		return
+	}
+
+keys:
+	for _, key := range []string{first, next} {
+		for _, listed := range r.offerIDCollisions[id] {
+			if listed == key {
+				continue keys
+			}
+		}
+		r.offerIDCollisions[id] = append(r.offerIDCollisions[id], key)
+	}
+}
+
+// addPositionError регистрирует ошибку обработки позиции.
+func (r *feedReport) addPositionError(e positionError) {
+
+	if r == nil {
+		return
+	}
+
+	r.positionErrors = append(r.positionErrors, e)
+}
+
+// positionErrorsCount возвращает количество позиций, обработка которых завершилась ошибкой.
+func (r *feedReport) positionErrorsCount() int {
+
+	if r == nil {
+		return 0
+	}
+
+	return len(r.positionErrors)
+}
+
+// positionErrorsExceeded сообщает, превышено ли допустимое количество ошибок обработки позиций.
+// Отрицательный limit означает отсутствие ограничения.
+func (r *feedReport) positionErrorsExceeded(limit int) bool {
+	return limit >= 0 && r.positionErrorsCount() > limit
+}
+
+// unmappedValues возвращает отсортированный список несопоставленных значений свойства prop.
+func (r *feedReport) unmappedValues(prop string) []string {
+
+	if r == nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(r.unmapped[prop]))
+	for value := range r.unmapped[prop] {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+// log выводит сводку замечаний. Параметр task идентифицирует задачу в журнале.
+func (r *feedReport) log(task string) {
+
+	if r == nil {
+		return
+	}
+
+	for _, e := range r.positionErrors {
+		log.Errorf("%s: ошибка обработки позиции %s|%s: %s\n%s", task, e.Brand, e.Number, e.Err, e.Stack)
+	}
+
+	if n := len(r.positionErrors); n > 0 {
+		log.Warnf("%s: позиций, пропущенных из-за ошибок: %d", task, n)
+	}
+
+	for _, prop := range sortedKeys(r.unmapped) {
+		values := r.unmappedValues(prop)
+		quoted := make([]string, 0, len(values))
+		for _, value := range values {
+			quoted = append(quoted, strconv.Quote(value)+" ("+strconv.Itoa(r.unmapped[prop][value])+")")
+		}
+		log.Warnf("%s: не найдено соответствие для значений свойства %s: %s", task, prop, strings.Join(quoted, ", "))
+	}
+
+	for _, group := range sortedKeys(r.missing) {
+		for _, prop := range sortedKeys(r.missing[group]) {
+			keys := r.missing[group][prop]
+			log.Warnf("%s: группа товаров %s, не заполнено свойство %s у %d позиций: %s",
+				task, group, prop, len(keys), strings.Join(truncateKeys(keys), ", "))
+		}
+	}
+
+	for _, prop := range sortedKeys(r.mismatches) {
+		keys := r.mismatches[prop]
+		log.Warnf("%s: неожиданный тип значения свойства %s у %d позиций: %s",
+			task, prop, len(keys), strings.Join(truncateKeys(keys), ", "))
+	}
+
+	for _, name := range sortedKeys(r.templateErrors) {
+		errs := r.templateErrors[name]
+		log.Warnf("%s: шаблон %s не выполнен у %d позиций, использован встроенный шаблон: %s",
+			task, name, len(errs), strings.Join(truncateKeys(errs), "; "))
+	}
+
+	if n := len(r.missingImages); n > 0 {
+		log.Warnf("%s: недоступных изображений: %d: %s", task, n, strings.Join(truncateKeys(r.missingImages), ", "))
+	}
+
+	if n := len(r.longTitles); n > 0 {
+		log.Warnf("%s: позиций с названием, обрезанным до %d символов: %d: %s",
+			task, titleRuneLimit, n, strings.Join(truncateKeys(r.longTitles), ", "))
+	}
+
+	if n := len(r.offerIDCollisions); n > 0 {
+		ids := sortedKeys(r.offerIDCollisions)
+		collisions := make([]string, 0, len(ids))
+		for _, id := range ids {
+			collisions = append(collisions, id+" ("+strings.Join(truncateKeys(r.offerIDCollisions[id]), ", ")+")")
+		}
+		log.Warnf("%s: совпадающих идентификаторов предложений: %d: %s", task, n, strings.Join(truncateKeys(collisions), "; "))
+	}
+}
+
+// truncateKeys ограничивает список позиций для вывода в журнал.
+func truncateKeys(keys []string) []string {
+
+	if len(keys) <= maxReportedKeys {
+		return keys
+	}
+
+	out := append([]string{}, keys[:maxReportedKeys]...)
+
+	return append(out, "...")
+}
+
+// sortedKeys возвращает отсортированные ключи словаря.
+func sortedKeys[V any](m map[string]V) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
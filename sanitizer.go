@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedDescriptionTags содержит теги, разрешённые Авито в описании объявления.
+var allowedDescriptionTags = map[string]bool{
+	"p":      true,
+	"br":     true,
+	"strong": true,
+	"em":     true,
+	"ul":     true,
+	"ol":     true,
+	"li":     true,
+}
+
+// descriptionTagAliases содержит теги, которые заменяются разрешёнными аналогами.
+var descriptionTagAliases = map[string]string{
+	"b": "strong",
+	"i": "em",
+}
+
+// droppedDescriptionTags содержит теги, которые удаляются вместе с содержимым.
+var droppedDescriptionTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"head":     true,
+	"title":    true,
+	"iframe":   true,
+	"noscript": true,
+	"template": true,
+}
+
+// descriptionTextReplacer экранирует текст описания и заменяет неразрывные пробелы обычными.
+var descriptionTextReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\u00a0", " ",
+)
+
+// sanitizeDescription оставляет в описании только разрешённые Авито теги без атрибутов.
+// Остальные теги удаляются с сохранением текста, а script, style и подобные - вместе с содержимым.
+// Незакрытые теги закрываются, лишние закрывающие теги удаляются.
+// Мнемоники декодируются, а в тексте экранируются только "&", "<" и ">".
+func sanitizeDescription(s string) string {
+
+	var (
+		b       strings.Builder
+		open    []string
+		dropped int
+	)
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// Токенизатор читает из строки, поэтому единственная ошибка - io.EOF.
+			break
+		}
+
+		token := z.Token()
+		name := token.Data
+		if alias, ok := descriptionTagAliases[name]; ok {
+			name = alias
+		}
+
+		switch tt {
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(descriptionTextReplacer.Replace(token.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedDescriptionTags[name] {
+				if tt == html.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			if dropped != 0 || !allowedDescriptionTags[name] {
+				continue
+			}
+			if name == "br" {
+				b.WriteString("<br/>")
+				continue
+			}
+			if name == "li" || name == "p" {
+				open = closeImpliedTag(&b, open, name)
+			}
+			b.WriteString("<" + name + ">")
+			if tt == html.SelfClosingTagToken {
+				b.WriteString("</" + name + ">")
+				continue
+			}
+			open = append(open, name)
+		case html.EndTagToken:
+			if droppedDescriptionTags[name] {
+				if dropped > 0 {
+					dropped--
+				}
+				continue
+			}
+			if dropped != 0 || !allowedDescriptionTags[name] || name == "br" {
+				continue
+			}
+			open = closeDescriptionTag(&b, open, name)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
+
+// closeDescriptionTag закрывает тег name и все теги, открытые после него.
+// Если тег name не открыт, закрывающий тег пропускается.
+func closeDescriptionTag(b *strings.Builder, open []string, name string) []string {
+
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] != name {
+			continue
+		}
+
+		for j := len(open) - 1; j >= i; j-- {
+			b.WriteString("</" + open[j] + ">")
+		}
+
+		return open[:i]
+	}
+
+	return open
+}
+
+// closeImpliedTag закрывает открытый тег li или p перед открытием такого же тега,
+// если он не вложен в список.
+func closeImpliedTag(b *strings.Builder, open []string, name string) []string {
+
+	for i := len(open) - 1; i >= 0; i-- {
+		switch open[i] {
+		case name:
+			return closeDescriptionTag(b, open, name)
+		case "ul", "ol":
+			return open
+		}
+	}
+
+	return open
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestSanitizeDescription(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Allowed tags kept",
+			in:   "Масло. <br/><ul><li>SAE: 5W-30.</li> <li>Volume: 4.</li></ul> ",
+			want: "Масло. <br/><ul><li>SAE: 5W-30.</li> <li>Volume: 4.</li></ul> ",
+		},
+		{
+			name: "Attributes removed",
+			in:   `<p class="x" onclick="alert(1)">Текст</p><br clear="all">`,
+			want: "<p>Текст</p><br/>",
+		},
+		{
+			name: "Aliases",
+			in:   "<b>Жирный</b> и <i>курсив</i>",
+			want: "<strong>Жирный</strong> и <em>курсив</em>",
+		},
+		{
+			name: "Unknown tags removed with text kept",
+			in:   `<div><span>Фильтр</span> <a href="http://x">ссылка</a></div>`,
+			want: "Фильтр ссылка",
+		},
+		{
+			name: "Script and style removed with content",
+			in:   "До<script>alert('x')</script><style>p{}</style> после",
+			want: "До после",
+		},
+		{
+			name: "Unclosed tags balanced",
+			in:   "<ul><li>Один<li>Два",
+			want: "<ul><li>Один</li><li>Два</li></ul>",
+		},
+		{
+			name: "Nested lists kept",
+			in:   "<ul><li>Один<ol><li>Вложенный</ol></li><p>Абзац<p>Ещё</ul>",
+			want: "<ul><li>Один<ol><li>Вложенный</li></ol></li><p>Абзац</p><p>Ещё</p></ul>",
+		},
+		{
+			name: "Overlapping tags closed",
+			in:   "<strong><em>Текст</strong> хвост</em>",
+			want: "<strong><em>Текст</em></strong> хвост",
+		},
+		{
+			name: "Stray end tags removed",
+			in:   "</p>Текст</li>",
+			want: "Текст",
+		},
+		{
+			name: "Entities decoded and normalized",
+			in:   "Размер&nbsp;16&quot; &laquo;Зима&raquo; A&amp;B C",
+			want: "Размер 16\" «Зима» A&amp;B C",
+		},
+		{
+			name: "Text comparison escaped",
+			in:   "Объём < 5 л & > 1 л",
+			want: "Объём &lt; 5 л &amp; &gt; 1 л",
+		},
+		{
+			name: "Comments removed",
+			in:   "Текст<!-- комментарий -->",
+			want: "Текст",
+		},
+		{
+			name: "Newlines kept",
+			in:   "Строка 1\nСтрока 2",
+			want: "Строка 1\nСтрока 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeDescription(tt.in); got != tt.want {
+				t.Errorf("sanitizeDescription(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// titleRuneLimit ограничивает длину тега <Title>.
+const titleRuneLimit = 50
+
+// titleAbbreviationsTable содержит имя справочника хранилища с сокращениями слов названия.
+const titleAbbreviationsTable = "TitleAbbreviation"
+
+// defaultTitleAbbreviations содержит встроенные сокращения слов названия в нижнем регистре.
+var defaultTitleAbbreviations = map[string]string{
+	"комплект":          "к-т",
+	"передний":          "пер.",
+	"передняя":          "пер.",
+	"переднее":          "пер.",
+	"передние":          "пер.",
+	"задний":            "задн.",
+	"задняя":            "задн.",
+	"заднее":            "задн.",
+	"задние":            "задн.",
+	"левый":             "лев.",
+	"левая":             "лев.",
+	"левое":             "лев.",
+	"левые":             "лев.",
+	"правый":            "прав.",
+	"правая":            "прав.",
+	"правое":            "прав.",
+	"правые":            "прав.",
+	"верхний":           "верх.",
+	"верхняя":           "верх.",
+	"нижний":            "нижн.",
+	"нижняя":            "нижн.",
+	"двигателя":         "двиг.",
+	"автомобиля":        "а/м",
+	"оригинальный":      "ориг.",
+	"универсальный":     "унив.",
+	"универсальная":     "унив.",
+	"синтетическое":     "синт.",
+	"полусинтетическое": "п/синт.",
+}
+
+// titleFuncWords содержит служебные слова, которые не остаются в названии без следующего слова.
+var titleFuncWords = map[string]bool{"и": true, "в": true, "к": true, "c": true, "с": true}
+
+// Ранги слов названия: при сокращении сначала удаляются слова с меньшим рангом.
+const (
+	titleRankDetail = iota
+	titleRankSize
+	titleRankModel
+	titleRankName
+	titleRankBrand
+	titleRankNumber
+)
+
+// titleKeys содержит значения, по которым определяются ранги слов названия.
+type titleKeys struct {
+	brand  string
+	number string
+	model  string
+	prefix string
+}
+
+// titleShortener сокращает названия предложений до titleRuneLimit символов.
+//
+// Сначала применяются сокращения слов, затем удаляются слова с наименьшим рангом,
+// начиная с конца названия: подробности описания, размеры, модель, наименование детали,
+// бренд и артикул. Если оставшееся слово длиннее titleRuneLimit, оно обрезается.
+type titleShortener struct {
+	abbreviations map[string]string
+}
+
+// newTitleShortener создаёт сокращатель названий с учётом справочника сокращений хранилища.
+func newTitleShortener() *titleShortener {
+	return newTitleShortenerFrom(PricegenStorage.GetAvitoSpec(titleAbbreviationsTable, "title"))
+}
+
+// newTitleShortenerFrom создаёт сокращатель названий со справочником сокращений stored,
+// дополняющим встроенный.
+func newTitleShortenerFrom(stored map[string]string) *titleShortener {
+
+	abbreviations := make(map[string]string, len(defaultTitleAbbreviations)+len(stored))
+	for word, abbr := range defaultTitleAbbreviations {
+		abbreviations[word] = abbr
+	}
+	for word, abbr := range stored {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.TrimSpace(abbr) != "" {
+			abbreviations[word] = strings.TrimSpace(abbr)
+		}
+	}
+
+	return &titleShortener{abbreviations: abbreviations}
+}
+
+// titleWord содержит слово названия и его ранг.
+type titleWord struct {
+	text string
+	rank int
+	key  bool // слово из значений titleKeys не сокращается
+}
+
+// shorten сокращает название title, если оно длиннее titleRuneLimit символов.
+// Возвращает false, если после удаления всех остальных слов пришлось обрезать слово с цифрами,
+// обычно артикул.
+func (s *titleShortener) shorten(title string, keys titleKeys) (string, bool) {
+
+	if utf8.RuneCountInString(title) <= titleRuneLimit {
+		return title, true
+	}
+
+	words := rankTitleWords(strings.Fields(title), keys)
+	if len(words) == 0 {
+		return "", true
+	}
+
+	for i := range words {
+		if !words[i].key {
+			words[i].text = s.abbreviate(words[i].text)
+		}
+	}
+
+	for len(words) > 1 && titleLength(words) > titleRuneLimit {
+		words = removeTitleWord(words)
+	}
+
+	ok := true
+	if len(words) == 1 {
+		if runes := []rune(words[0].text); len(runes) > titleRuneLimit {
+			ok = !hasDigit(words[0].text)
+			words[0].text = string(runes[:titleRuneLimit])
+		}
+	}
+
+	return joinTitleWords(words), ok
+}
+
+// abbreviate возвращает сокращение слова word, сохраняя заглавную первую букву и знаки препинания после слова.
+func (s *titleShortener) abbreviate(word string) string {
+
+	base := strings.TrimRight(word, ",;:")
+	suffix := word[len(base):]
+
+	abbr, ok := s.abbreviations[strings.ToLower(base)]
+	if !ok {
+		return word
+	}
+
+	if r, _ := utf8.DecodeRuneInString(base); unicode.IsUpper(r) {
+		first, size := utf8.DecodeRuneInString(abbr)
+		abbr = string(unicode.ToUpper(first)) + abbr[size:]
+	}
+
+	return abbr + suffix
+}
+
+// rankTitleWords определяет ранги слов названия.
+func rankTitleWords(fields []string, keys titleKeys) []titleWord {
+
+	ranks := make(map[string]int)
+	addKey := func(phrase string, rank int) {
+		for _, w := range strings.Fields(strings.ToLower(phrase)) {
+			if r, ok := ranks[w]; !ok || r < rank {
+				ranks[w] = rank
+			}
+		}
+	}
+	addKey(keys.prefix, titleRankName)
+	addKey(keys.model, titleRankModel)
+	addKey(keys.brand, titleRankBrand)
+	addKey(keys.number, titleRankNumber)
+
+	words := make([]titleWord, 0, len(fields))
+	nameFound := false
+	for _, field := range fields {
+		w := titleWord{text: field, rank: titleRankDetail}
+		if rank, ok := ranks[strings.ToLower(strings.TrimRight(field, ",;:"))]; ok {
+			w.rank = rank
+			w.key = true
+		} else if !nameFound && !titleFuncWords[strings.ToLower(field)] {
+			w.rank = titleRankName
+			nameFound = true
+		} else if hasDigit(field) {
+			w.rank = titleRankSize
+		}
+		words = append(words, w)
+	}
+
+	return words
+}
+
+// removeTitleWord удаляет последнее слово с наименьшим рангом
+// вместе со служебным словом перед ним.
+func removeTitleWord(words []titleWord) []titleWord {
+
+	idx := len(words) - 1
+	for i := len(words) - 1; i >= 0; i-- {
+		if words[i].rank < words[idx].rank {
+			idx = i
+		}
+	}
+
+	from := idx
+	if from > 0 && titleFuncWords[strings.ToLower(words[from-1].text)] {
+		from--
+	}
+
+	return append(words[:from], words[idx+1:]...)
+}
+
+// titleLength возвращает длину названия из слов words.
+func titleLength(words []titleWord) int {
+	return utf8.RuneCountInString(joinTitleWords(words))
+}
+
+// joinTitleWords собирает название из слов, удаляя служебные слова и знаки препинания в конце.
+func joinTitleWords(words []titleWord) string {
+
+	for len(words) > 0 && titleFuncWords[strings.ToLower(words[len(words)-1].text)] {
+		words = words[:len(words)-1]
+	}
+
+	parts := make([]string, 0, len(words))
+	for _, w := range words {
+		parts = append(parts, w.text)
+	}
+
+	return strings.TrimRight(strings.Join(parts, " "), ",;: ")
+}
+
+// hasDigit сообщает, содержит ли слово цифры.
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTitleShortenerShorten(t *testing.T) {
+
+	s := newTitleShortenerFrom(map[string]string{" Стойка ": "ст.", "пустое": " "})
+
+	tests := []struct {
+		name    string
+		title   string
+		keys    titleKeys
+		want    string
+		tooLong bool
+	}{
+		{
+			name:  "Short title unchanged",
+			title: "Комплект  колодок 0986 Bosch",
+			keys:  titleKeys{brand: "Bosch", number: "0986"},
+			want:  "Комплект  колодок 0986 Bosch",
+		},
+		{
+			name:  "Abbreviations before removing",
+			title: "Комплект тормозных колодок передний левый 0986494 Bosch",
+			keys:  titleKeys{brand: "Bosch", number: "0986494"},
+			want:  "К-т тормозных колодок пер. лев. 0986494 Bosch",
+		},
+		{
+			name:  "Stored abbreviation",
+			title: "Стойка стабилизатора передняя правая усиленная 0986494 Bosch",
+			keys:  titleKeys{brand: "Bosch", number: "0986494"},
+			want:  "Ст. стабилизатора пер. прав. 0986494 Bosch",
+		},
+		{
+			name:  "Keeps number and brand",
+			title: "Фильтр масляный для двигателя с турбонаддувом и интеркулером W 712/95 Mann-Filter",
+			keys:  titleKeys{brand: "Mann-Filter", number: "W 712/95"},
+			want:  "Фильтр масляный для двиг. W 712/95 Mann-Filter",
+		},
+		{
+			name:  "Drops size before model",
+			title: "Шины Nokian Hakkapeliitta R5 SUV 265/65R17 116R XL шипованные",
+			keys:  titleKeys{brand: "Nokian", model: "Hakkapeliitta R5 SUV"},
+			want:  "Шины Nokian Hakkapeliitta R5 SUV 265/65R17 116R XL",
+		},
+		{
+			name:  "Used prefix kept",
+			title: "Б/у Фара передняя левая светодиодная с корректором и омывателем 8W0941033 Audi",
+			keys:  titleKeys{brand: "Audi", number: "8W0941033", prefix: "Б/у"},
+			want:  "Б/у Фара пер. лев. светодиодная 8W0941033 Audi",
+		},
+		{
+			name:    "Oversize number cut",
+			title:   "1234567890123456789012345678901234567890123456789012345",
+			keys:    titleKeys{number: "1234567890123456789012345678901234567890123456789012345"},
+			want:    "12345678901234567890123456789012345678901234567890",
+			tooLong: true,
+		},
+		{
+			name:  "Brand dropped before oversize number",
+			title: "Фильтр 1234567890123456789012345678901234567890123456 Bosch",
+			keys:  titleKeys{brand: "Bosch", number: "1234567890123456789012345678901234567890123456"},
+			want:  "1234567890123456789012345678901234567890123456",
+		},
+		{
+			name:  "Long word cut",
+			title: "Абвгдежзийклмнопрстуфхцчшщъыьэюяабвгдежзийклмнопрстуфхцчшщъыьэюя",
+			want:  "Абвгдежзийклмнопрстуфхцчшщъыьэюяабвгдежзийклмнопрс",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.shorten(tt.title, tt.keys)
+			if got != tt.want || ok == tt.tooLong {
+				t.Errorf("shorten() = %q, %v, want %q, %v", got, ok, tt.want, !tt.tooLong)
+			}
+			if n := utf8.RuneCountInString(got); n > titleRuneLimit {
+				t.Errorf("shorten() length = %d, want <= %d", n, titleRuneLimit)
+			}
+		})
+	}
+}
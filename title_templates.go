@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Шаблон названия предложения состоит из текста, подстановок и условных сегментов:
+//
+//	Шины {brand} {catalog_model} [{width}[/{height}]R{diameter}] [{load_index}{speed_index}]
+//
+// Подстановка {name} заменяется полем позиции (brand, number, description, category)
+// или значением свойства товара name. Условный сегмент [...] выводится,
+// только если заполнены все подстановки внутри него; сегменты могут быть вложенными.
+// После подстановки повторяющиеся пробелы схлопываются, а пробелы и запятые по краям удаляются.
+
+// defaultTitleTemplate используется для категорий без встроенного шаблона.
+const defaultTitleTemplate = "{description} {number} {brand}"
+
+// builtinTitleTemplates содержит встроенные шаблоны названий по категориям товаров.
+var builtinTitleTemplates = map[string]string{
+	"tires": "Шины {brand} {catalog_model} [{width}[/{height}]R{diameter}] {load_index} {speed_index}",
+	"disks": "Диск {brand}[, {catalog_model}] [{width}x{diameter}][/{holes}x{pcd}][ET{et}] {hub_diameter}",
+	"oils":  "{description} {brand}",
+}
+
+// titleNode содержит элемент шаблона названия: текст, подстановку или условный сегмент.
+type titleNode struct {
+	text    string
+	field   string
+	segment []titleNode
+}
+
+// titleTemplate содержит разобранный шаблон названия.
+type titleTemplate []titleNode
+
+// parseTitleTemplate разбирает шаблон названия s.
+func parseTitleTemplate(s string) (titleTemplate, error) {
+
+	stack := [][]titleNode{nil}
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() != 0 {
+			stack[len(stack)-1] = append(stack[len(stack)-1], titleNode{text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, errors.Errorf("незакрытая подстановка в позиции %d", i)
+			}
+
+			field := strings.TrimSpace(s[i+1 : i+end])
+			if field == "" || strings.ContainsAny(field, "{[]") {
+				return nil, errors.Errorf("некорректная подстановка %q", s[i:i+end+1])
+			}
+
+			flush()
+			stack[len(stack)-1] = append(stack[len(stack)-1], titleNode{field: field})
+			i += end
+		case '}':
+			return nil, errors.Errorf("лишняя закрывающая скобка в позиции %d", i)
+		case '[':
+			flush()
+			stack = append(stack, nil)
+		case ']':
+			if len(stack) == 1 {
+				return nil, errors.Errorf("лишняя закрывающая скобка сегмента в позиции %d", i)
+			}
+
+			flush()
+			segment := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1] = append(stack[len(stack)-1], titleNode{segment: segment})
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, errors.New("незакрытый сегмент")
+	}
+	flush()
+
+	return titleTemplate(stack[0]), nil
+}
+
+// mustParseTitleTemplate разбирает встроенный шаблон названия.
+func mustParseTitleTemplate(s string) titleTemplate {
+
+	t, err := parseTitleTemplate(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// execute формирует название по шаблону. value возвращает значение подстановки.
+func (t titleTemplate) execute(value func(field string) string) string {
+
+	s, _ := renderTitleNodes(t, value)
+
+	s = strings.Join(strings.Fields(s), " ")
+
+	return strings.Trim(s, " ,")
+}
+
+// renderTitleNodes формирует текст элементов шаблона.
+// Возвращает false, если хотя бы одна подстановка вне вложенных сегментов пустая.
+func renderTitleNodes(nodes []titleNode, value func(field string) string) (string, bool) {
+
+	var b strings.Builder
+	complete := true
+
+	for _, node := range nodes {
+		switch {
+		case node.field != "":
+			v := strings.TrimSpace(value(node.field))
+			if v == "" {
+				complete = false
+			}
+			b.WriteString(v)
+		case node.segment != nil:
+			if s, ok := renderTitleNodes(node.segment, value); ok {
+				b.WriteString(s)
+			}
+		default:
+			b.WriteString(node.text)
+		}
+	}
+
+	return b.String(), complete
+}
+
+// titleTemplates содержит шаблоны названий предложений одной задачи.
+type titleTemplates struct {
+	client  map[string]titleTemplate
+	builtin map[string]titleTemplate
+	def     titleTemplate
+}
+
+// newTitleTemplates создаёт шаблоны названий с учётом шаблонов клиента client:
+// категория или группа товаров -> шаблон, ключ globalGoodsGroup - шаблон для остальных категорий.
+// Шаблоны клиента с ошибками не используются.
+func newTitleTemplates(client map[string]string) *titleTemplates {
+
+	t := &titleTemplates{
+		client:  make(map[string]titleTemplate),
+		builtin: make(map[string]titleTemplate),
+		def:     mustParseTitleTemplate(defaultTitleTemplate),
+	}
+
+	for category, s := range builtinTitleTemplates {
+		t.builtin[category] = mustParseTitleTemplate(s)
+	}
+
+	for _, category := range sortedKeys(client) {
+		tmpl, err := parseTitleTemplate(client[category])
+		if err != nil {
+			log.Errorf("Шаблон названия клиента для %s не используется: %v", category, err)
+			continue
+		}
+		t.client[category] = tmpl
+	}
+
+	return t
+}
+
+// lookup возвращает шаблон названия позиции pos.
+// Шаблоны клиента ищутся по категории, группе товаров и ключу globalGoodsGroup,
+// встроенные шаблоны - по категории.
+func (t *titleTemplates) lookup(pos position) titleTemplate {
+
+	for _, key := range []string{pos.Category, pos.GoodsGroupCode, globalGoodsGroup} {
+		if tmpl, ok := t.client[key]; ok && key != "" {
+			return tmpl
+		}
+	}
+
+	if tmpl, ok := t.builtin[pos.Category]; ok {
+		return tmpl
+	}
+
+	return t.def
+}
+
+// build формирует название позиции pos по шаблону.
+func (t *titleTemplates) build(pos position, props map[string]any) string {
+
+	p := newPropReader(props, "", nil)
+
+	return t.lookup(pos).execute(func(field string) string {
+		switch field {
+		case "brand":
+			return pos.Brand
+		case "number":
+			if pos.CustomNumber != "" {
+				return pos.CustomNumber
+			}
+			return pos.Number
+		case "description":
+			return pos.Description
+		case "category":
+			return pos.Category
+		}
+
+		v, _ := p.String(field)
+
+		return v
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+func TestTitleTemplatesBuild(t *testing.T) {
+
+	titles := newTitleTemplates(map[string]string{
+		"wipers":         "Щётка {brand} [{length1} мм] {number}",
+		"batteries":      "Аккумулятор [{brand}",
+		globalGoodsGroup: "{description}[, {brand}] {number}",
+	})
+	builtin := newTitleTemplates(nil)
+
+	tires := Properties{
+		"catalog_model": "Nordman 7",
+		"width":         205.0,
+		"height":        "55",
+		"diameter":      16,
+		"load_index":    "94",
+		"speed_index":   "T",
+	}
+	disks := Properties{
+		"catalog_model": "K-123",
+		"width":         "6.5",
+		"diameter":      "16",
+		"holes":         "5",
+		"pcd":           "114.3",
+		"et":            "45",
+		"hub_diameter":  "67.1",
+	}
+
+	tests := []struct {
+		name   string
+		titles *titleTemplates
+		pos    position
+		props  Properties
+		want   string
+	}{
+		{
+			name:   "Tires",
+			titles: builtin,
+			pos:    position{Brand: "Nokian", Category: "tires"},
+			props:  tires,
+			want:   "Шины Nokian Nordman 7 205/55R16 94 T",
+		},
+		{
+			name:   "Tires without height and indexes",
+			titles: builtin,
+			pos:    position{Brand: "Nokian", Category: "tires"},
+			props:  Properties{"catalog_model": "Nordman 7", "width": "205", "diameter": "16"},
+			want:   "Шины Nokian Nordman 7 205R16",
+		},
+		{
+			name:   "Tires without sizes",
+			titles: builtin,
+			pos:    position{Brand: "Nokian", Category: "tires"},
+			props:  Properties{},
+			want:   "Шины Nokian",
+		},
+		{
+			name:   "Disks",
+			titles: builtin,
+			pos:    position{Brand: "Скад", Category: "disks"},
+			props:  disks,
+			want:   "Диск Скад, K-123 6.5x16/5x114.3ET45 67.1",
+		},
+		{
+			name:   "Disks without model and bolts",
+			titles: builtin,
+			pos:    position{Brand: "Скад", Category: "disks"},
+			props:  Properties{"width": "6.5", "diameter": "16", "et": "45"},
+			want:   "Диск Скад 6.5x16ET45",
+		},
+		{
+			name:   "Oils",
+			titles: builtin,
+			pos:    position{Brand: "Shell", Number: "550040", Description: "Масло моторное", Category: "oils"},
+			want:   "Масло моторное Shell",
+		},
+		{
+			name:   "Default with custom number",
+			titles: builtin,
+			pos:    position{Brand: "Bosch", Number: "0 986", CustomNumber: "0986", Description: "Фильтр"},
+			want:   "Фильтр 0986 Bosch",
+		},
+		{
+			name:   "Client template by goods group",
+			titles: titles,
+			pos:    position{Brand: "Bosch", Number: "3397", GoodsGroupCode: "wipers"},
+			props:  Properties{"length1": []any{"600"}},
+			want:   "Щётка Bosch 600 мм 3397",
+		},
+		{
+			name:   "Client template collapses segment",
+			titles: titles,
+			pos:    position{Brand: "Bosch", Number: "3397", GoodsGroupCode: "wipers"},
+			want:   "Щётка Bosch 3397",
+		},
+		{
+			name:   "Client default template",
+			titles: titles,
+			pos:    position{Brand: "Mann", Number: "W 712", Description: "Фильтр", Category: "tires"},
+			want:   "Фильтр, Mann W 712",
+		},
+		{
+			name:   "Invalid client template ignored",
+			titles: titles,
+			pos:    position{Number: "6CT", Description: "Аккумулятор", GoodsGroupCode: "batteries"},
+			want:   "Аккумулятор 6CT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.titles.build(tt.pos, tt.props); got != tt.want {
+				t.Errorf("build() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTitleTemplateErrors(t *testing.T) {
+
+	for _, s := range []string{"{brand", "brand}", "[{brand}", "{brand}]", "{}", "{a[b}"} {
+		if _, err := parseTitleTemplate(s); err == nil {
+			t.Errorf("parseTitleTemplate(%q) error = nil, want error", s)
+		}
+	}
+}
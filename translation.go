@@ -0,0 +1,107 @@
+package main
+
+import "strings"
+
+// globalGoodsGroup содержит ключ goodsGroupPack с переводами,
+// общими для всех групп товаров.
+const globalGoodsGroup = "*"
+
+// translationScopeSep разделяет имя свойства и значение в ключе перевода,
+// ограниченного одним свойством: "viscosity|synthetic".
+const translationScopeSep = "|"
+
+// untranslatedProps содержит служебные свойства, значения которых не переводятся.
+var untranslatedProps = map[string]bool{
+	"goods_group": true,
+	"images":      true,
+}
+
+// propTranslator переводит значения свойств товара из article api.
+//
+// Переводы организованы в слои, которые просматриваются от частного к общему:
+// переводы клиента, переводы группы товаров, глобальные переводы.
+// В каждом слое перевод, ограниченный свойством ("имя|значение"),
+// имеет приоритет над переводом значения для всех свойств.
+// Пустой перевод не учитывается, и поиск продолжается в следующем слое.
+type propTranslator struct {
+	goodsGroupPack map[string]map[string]map[string]string
+	client         map[string]string
+}
+
+func newPropTranslator(goodsGroupPack map[string]map[string]map[string]string, client map[string]string) *propTranslator {
+	return &propTranslator{goodsGroupPack: goodsGroupPack, client: client}
+}
+
+// translate переводит значения свойств props группы товаров group на месте.
+func (t *propTranslator) translate(props map[string]any, group string) {
+
+	layers := []map[string]string{
+		t.client,
+		t.goodsGroupPack[group]["translatedprops"],
+		t.goodsGroupPack[globalGoodsGroup]["translatedprops"],
+	}
+
+	for name, value := range props {
+		if untranslatedProps[name] {
+			continue
+		}
+		props[name] = translateValue(layers, name, value)
+	}
+}
+
+// translateValue переводит значение свойства name, включая элементы вложенных массивов.
+// Числа переводятся только по ключу "name|значение", чтобы перевод одного свойства
+// не подменял числовые значения других; если перевода нет, число не изменяется.
+func translateValue(layers []map[string]string, name string, value any) any {
+
+	switch v := value.(type) {
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			out = append(out, translateValue(layers, name, item))
+		}
+		return out
+	case string:
+		if translated, ok := lookupTranslation(layers, name, v); ok {
+			return translated
+		}
+	case float64, float32, int:
+		str, _ := scalarString(v)
+		if translated, ok := lookupScopedTranslation(layers, name, str); ok {
+			return translated
+		}
+	}
+
+	return value
+}
+
+// lookupTranslation ищет непустой перевод значения свойства name в слоях layers.
+func lookupTranslation(layers []map[string]string, name, value string) (string, bool) {
+
+	scoped := name + translationScopeSep + value
+	for _, layer := range layers {
+		if translated := layer[scoped]; strings.TrimSpace(translated) != "" {
+			return translated, true
+		}
+
+		if translated := layer[value]; strings.TrimSpace(translated) != "" {
+			return translated, true
+		}
+	}
+
+	return "", false
+}
+
+// lookupScopedTranslation ищет непустой перевод значения свойства name
+// только по ключам вида "name|значение".
+func lookupScopedTranslation(layers []map[string]string, name, value string) (string, bool) {
+
+	scoped := name + translationScopeSep + value
+	for _, layer := range layers {
+		if translated := layer[scoped]; strings.TrimSpace(translated) != "" {
+			return translated, true
+		}
+	}
+
+	return "", false
+}
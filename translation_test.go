@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPropTranslatorTranslate(t *testing.T) {
+
+	goodsGroupPack := map[string]map[string]map[string]string{
+		globalGoodsGroup: {
+			"translatedprops": map[string]string{
+				"synthetic": "синтетика",
+				"summer":    "летняя",
+				"1":         "одна",
+				"doors|4":   "четыре",
+			},
+		},
+		"oils": {
+			"translatedprops": map[string]string{
+				"viscosity|synthetic": "синтетическая вязкость",
+				"Second":              "",
+				"First":               "Первая",
+			},
+		},
+	}
+
+	client := map[string]string{
+		"summer":            "лето",
+		"oem_spec|First":    "Первая (клиент)",
+		"goods_group|oils":  "масла",
+		"pack_count|2":      "две",
+		"nested|inner":      "внутренний",
+		"oil_type|unusable": "",
+	}
+
+	props := map[string]any{
+		"goods_group": "oils",
+		"viscosity":   "synthetic",
+		"oil_type":    "synthetic",
+		"season":      "summer",
+		"oem_spec":    []any{"First", "Second", "Third"},
+		"pack_count":  2.0,
+		"holes":       1,
+		"doors":       4,
+		"nested":      []any{[]any{"inner", 5.5}},
+		"flag":        true,
+	}
+
+	newPropTranslator(goodsGroupPack, client).translate(props, "oils")
+
+	want := map[string]any{
+		"goods_group": "oils",
+		"viscosity":   "синтетическая вязкость",
+		"oil_type":    "синтетика",
+		"season":      "лето",
+		"oem_spec":    []any{"Первая (клиент)", "Second", "Third"},
+		"pack_count":  "две",
+		"holes":       1,
+		"doors":       "четыре",
+		"nested":      []any{[]any{"внутренний", 5.5}},
+		"flag":        true,
+	}
+
+	if diff := cmp.Diff(want, props); diff != "" {
+		t.Errorf("translate() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// quantityKind обозначает физическую величину, к которой относится единица измерения.
+type quantityKind int
+
+const (
+	kindVolume quantityKind = iota + 1
+	kindLength
+	kindWeight
+	kindVoltage
+	kindCapacity
+	kindCurrent
+)
+
+// unitDef описывает единицу измерения: величину и множитель для перевода в базовую единицу
+// (литр, миллиметр, килограмм, вольт, ампер-час, ампер).
+type unitDef struct {
+	kind   quantityKind
+	factor float64
+}
+
+// unitDefs содержит известные написания единиц измерения в нижнем регистре.
+var unitDefs = map[string]unitDef{
+	"л":      {kindVolume, 1},
+	"l":      {kindVolume, 1},
+	"литр":   {kindVolume, 1},
+	"литра":  {kindVolume, 1},
+	"литров": {kindVolume, 1},
+	"мл":     {kindVolume, 0.001},
+	"ml":     {kindVolume, 0.001},
+	"мм":     {kindLength, 1},
+	"mm":     {kindLength, 1},
+	"см":     {kindLength, 10},
+	"cm":     {kindLength, 10},
+	"м":      {kindLength, 1000},
+	"m":      {kindLength, 1000},
+	"дюйм":   {kindLength, 25.4},
+	"дюйма":  {kindLength, 25.4},
+	"дюймов": {kindLength, 25.4},
+	"in":     {kindLength, 25.4},
+	"\"":     {kindLength, 25.4},
+	"″":      {kindLength, 25.4},
+	"кг":     {kindWeight, 1},
+	"kg":     {kindWeight, 1},
+	"г":      {kindWeight, 0.001},
+	"гр":     {kindWeight, 0.001},
+	"g":      {kindWeight, 0.001},
+	"в":      {kindVoltage, 1},
+	"v":      {kindVoltage, 1},
+	"v/в":    {kindVoltage, 1},
+	"ач":     {kindCapacity, 1},
+	"а·ч":    {kindCapacity, 1},
+	"ah":     {kindCapacity, 1},
+	"а":      {kindCurrent, 1},
+	"a":      {kindCurrent, 1},
+}
+
+var regexpQuantity = regexp.MustCompile(`^([+-]?\d+(?:[.,]\d+)?)\s*([^\d\s]*)$`)
+
+// quantity содержит числовое значение свойства и единицу измерения, в которой оно указано.
+// Пустая единица означает, что значение указано в единицах по умолчанию для тега.
+type quantity struct {
+	value float64
+	unit  string
+}
+
+// parseQuantity разбирает значение свойства из article api: число или строку вида "4.0", "4,0 л", "500мл".
+func parseQuantity(v any) (quantity, bool) {
+
+	switch val := v.(type) {
+	case float64:
+		return quantity{value: val}, true
+	case float32:
+		return quantity{value: float64(val)}, true
+	case int:
+		return quantity{value: float64(val)}, true
+	case string:
+		s := strings.TrimSpace(strings.ReplaceAll(val, "\u00a0", " "))
+		m := regexpQuantity.FindStringSubmatch(s)
+		if m == nil {
+			return quantity{}, false
+		}
+
+		value, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			return quantity{}, false
+		}
+
+		unit := strings.TrimSuffix(strings.ToLower(m[2]), ".")
+		if _, ok := unitDefs[unit]; unit != "" && !ok {
+			return quantity{}, false
+		}
+
+		return quantity{value: value, unit: unit}, true
+	default:
+		return quantity{}, false
+	}
+}
+
+// in переводит значение в единицу target.
+// Возвращает false, если единицы относятся к разным величинам.
+func (q quantity) in(target string) (float64, bool) {
+
+	if q.unit == "" || q.unit == target {
+		return q.value, true
+	}
+
+	from, okFrom := unitDefs[q.unit]
+	to, okTo := unitDefs[target]
+	if !okFrom || !okTo || from.kind != to.kind {
+		return 0, false
+	}
+
+	return q.value * from.factor / to.factor, true
+}
+
+// measure описывает, в какой единице и с какой точностью выводится значение тега.
+type measure struct {
+	unit      string
+	precision int
+	withUnit  bool
+}
+
+var (
+	// volumeTag используется для тега <Volume>: "4 л", "0,946 л".
+	volumeTag = measure{unit: "л", precision: 3, withUnit: true}
+	// millimetersTag используется для габаритов и длин в миллиметрах.
+	millimetersTag = measure{unit: "мм", precision: 0}
+	// voltageTag используется для тега <Voltage>.
+	voltageTag = measure{unit: "в", precision: 1}
+	// capacityTag используется для тега <Capacity>.
+	capacityTag = measure{unit: "ач", precision: 1}
+	// currentTag используется для тега <DCL>.
+	currentTag = measure{unit: "а", precision: 0}
+	// inchesTag используется для посадочных диаметров в дюймах.
+	inchesTag = measure{unit: "дюйм", precision: 1}
+)
+
+// paramMeasures задаёт единицы измерения свойств в списке параметров описания.
+var paramMeasures = map[string]measure{
+	"liquid_volume": {unit: "л", precision: 3, withUnit: true},
+	"weight":        {unit: "кг", precision: 3},
+	"height_mm":     {unit: "мм", precision: 0},
+	"length1":       {unit: "мм", precision: 0},
+	"length2":       {unit: "мм", precision: 0},
+}
+
+// value возвращает значение свойства в единице измерения m.
+func (m measure) value(v any) (float64, bool) {
+
+	q, ok := parseQuantity(v)
+	if !ok {
+		return 0, false
+	}
+
+	return q.in(m.unit)
+}
+
+// format возвращает значение свойства в единице измерения m
+// с десятичным разделителем "запятая".
+// Если значение не удалось разобрать, возвращается исходная строка.
+func (m measure) format(v any) string {
+
+	value, ok := m.value(v)
+	if !ok {
+		return replaceSeparatorToComma(strings.TrimSpace(getString(v)))
+	}
+
+	s := formatNumber(value, m.precision)
+	if m.withUnit {
+		s += " " + m.unit
+	}
+
+	return s
+}
+
+// formatNumber форматирует число с десятичным разделителем "запятая",
+// не более чем с precision знаками после запятой и без незначащих нулей.
+func formatNumber(v float64, precision int) string {
+
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+
+	if s == "-0" {
+		s = "0"
+	}
+
+	return replaceSeparatorToComma(s)
+}
+
+// roundToInt возвращает значение свойства в единице измерения m, округлённое до целого.
+func (m measure) roundToInt(v any) (int, bool) {
+
+	value, ok := m.value(v)
+	if !ok {
+		return 0, false
+	}
+
+	return int(math.Round(value)), true
+}
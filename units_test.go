@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestMeasureFormat(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		measure measure
+		value   any
+		want    string
+	}{
+		{name: "Volume string with dot", measure: volumeTag, value: "4.0", want: "4 л"},
+		{name: "Volume string with comma", measure: volumeTag, value: "0,946", want: "0,946 л"},
+		{name: "Volume already with unit", measure: volumeTag, value: "1.5 л", want: "1,5 л"},
+		{name: "Volume in milliliters", measure: volumeTag, value: "500 мл", want: "0,5 л"},
+		{name: "Volume in milliliters without space", measure: volumeTag, value: "250ml", want: "0,25 л"},
+		{name: "Volume as float", measure: volumeTag, value: 4.0, want: "4 л"},
+		{name: "Volume as int", measure: volumeTag, value: 20, want: "20 л"},
+		{name: "Volume with unknown unit", measure: volumeTag, value: "4 банки", want: "4 банки"},
+		{name: "Volume with other quantity", measure: volumeTag, value: "4 кг", want: "4 кг"},
+		{name: "Length in centimeters", measure: millimetersTag, value: "27,8 см", want: "278"},
+		{name: "Length in millimeters", measure: millimetersTag, value: "175", want: "175"},
+		{name: "Voltage with latin unit", measure: voltageTag, value: "12V", want: "12"},
+		{name: "Voltage with both units", measure: voltageTag, value: "12 V/В", want: "12"},
+		{name: "Capacity", measure: capacityTag, value: "60 Ач", want: "60"},
+		{name: "Current", measure: currentTag, value: "540A", want: "540"},
+		{name: "Non-breaking space", measure: volumeTag, value: "5\u00a0л", want: "5 л"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.measure.format(tt.value); got != tt.want {
+				t.Errorf("format(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBrushLength(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		value any
+		want  int
+	}{
+		{name: "String", value: "600", want: 600},
+		{name: "Int", value: 450, want: 450},
+		{name: "Float", value: 475.0, want: 475},
+		{name: "Centimeters", value: "60 см", want: 600},
+		{name: "Invalid", value: "long", want: 0},
+		{name: "Nil", value: nil, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBrushLength(tt.value, "1"); got != tt.want {
+				t.Errorf("getBrushLength(%v) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
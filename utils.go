@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -14,10 +15,6 @@ const (
 	// установленный согласно документации Авито.
 	// См. https://www.avito.ru/autoload/documentation/templates/67029?fileFormat=xml#field-Description
 	descCharacterLimit = 7500
-
-	// trimmedDescCharacterLimit содержит лимит символов
-	// для обрезанного тега Description.
-	trimmedDescCharacterLimit = 7497
 )
 
 var (
@@ -26,12 +23,6 @@ var (
 	words    = regexp.MustCompile("[\\p{L}\\d_]+")
 )
 
-// encMap - константный массив для функции encode4chars.
-var encMap = map[string]string{
-	"0": "7", "1": "8", "2": "9", "3": "a", "4": "b", "5": "c", "6": "d", "7": "e",
-	"8": "f", "9": "0", "a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6",
-}
-
 func createTmpDir() (string, error) {
 
 	var err error
@@ -83,20 +74,26 @@ func caseInsensitiveContains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
 
-func buildFinalOfferDescription(descr, salesConditions string) string {
+// buildFinalOfferDescription формирует значение тега <Description> не длиннее descCharacterLimit символов.
+func buildFinalOfferDescription(d string) string {
+
+	d = replaceDescriptionNewlines(d)
+
+	if utf8.RuneCountInString(d) > descCharacterLimit {
+		return truncateDescriptionHTML(d, descCharacterLimit)
+	}
+
+	return d
+}
 
-	d := descr + salesConditions
+// replaceDescriptionNewlines заменяет переводы строк в описании тегом <br/>.
+func replaceDescriptionNewlines(d string) string {
 
 	d = regexpN.ReplaceAllString(d, "\n")
 	d = regexpRN.ReplaceAllString(d, "\r\n")
 	d = strings.ReplaceAll(d, "\r\n", "\n")
-	d = strings.ReplaceAll(d, "\n", "<br/>")
-
-	if len([]rune(d)) > descCharacterLimit {
-		return string([]rune(d)[0:trimmedDescCharacterLimit]) + "..."
-	}
 
-	return d
+	return strings.ReplaceAll(d, "\n", "<br/>")
 }
 
 // replaceModAutorus преобразовывает части формирующегося URL для альтернативного
@@ -144,39 +141,6 @@ func getString(i any) string {
 	}
 }
 
-// encode4chars кодирует имя картинки по ТЗ от аналитики.
-//
-// ТЗ в виде комментариев в telegram-чате от PVA (заявки нет).
-// =======
-// Алгоритм модификации имени картинки
-//
-// Нормальное имя: 05375f5bf51cd0207d02e5655f14e89cb.jpeg
-// 34 символа в имени без точки и расширения
-//
-// Модифицированное имя: 05375f_5bf51c_d0207d_02e565_5f14e89cb.jpeg
-// 38 символов в имени без точки и расширения
-// Вместо символа "_" будет рандомный символ из набора 0-9a-f
-// Позиции в которые ВСТАВЛЯЮТСЯ символы: 7,14,21,28
-//
-// Вместо символа "_" будет сдвинутый на +7 символ из позиций 24,32,6,12
-// Позиции в которые ВСТАВЛЯЮТСЯ символы: 7,14,21,28
-// Сдвиг +7, это значит 0=7, 1=8, 2=9, 3=a, 4=b ... f=6
-//
-// Т.е. берутся символы из позиций 24,32,6,12
-// сдвигаются и вставляются на позиции 7,14,21,28
-func encode4chars(s string) string {
-
-	if len(s) < 34 {
-		return s
-	}
-
-	return s[0:6] + encMap[s[24:25]] +
-		s[6:12] + encMap[s[32:33]] +
-		s[12:18] + encMap[s[6:7]] +
-		s[18:24] + encMap[s[12:13]] +
-		s[24:]
-}
-
 func getWordsFrom(text string) []string {
 	return words.FindAllString(text, -1)
 }